@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultGenerateMaxCount  = 100000
+	defaultGenerateBatchSize = 500
+)
+
+var (
+	sampleVerbs      = []string{"Review", "Deploy", "Refactor", "Document", "Test", "Fix", "Design", "Migrate"}
+	sampleSubjects   = []string{"billing service", "login flow", "search index", "dashboard", "API gateway", "mobile app", "CI pipeline", "database schema"}
+	sampleStatuses   = []string{"pending", "in_progress", "completed"}
+	samplePriorities = []string{"low", "medium", "high"}
+)
+
+// GenerateProgress is streamed as one JSON line per committed batch, followed
+// by a final line with Done set and the timing stats filled in.
+type GenerateProgress struct {
+	Inserted       int     `json:"inserted"`
+	Requested      int     `json:"requested"`
+	Done           bool    `json:"done"`
+	DurationMS     int64   `json:"duration_ms,omitempty"`
+	TasksPerSecond float64 `json:"tasks_per_second,omitempty"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// nonProductionOnly hides the route entirely when running in production.
+func nonProductionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if config().App.Environment == "production" {
+			respondError(c, http.StatusNotFound, errCodeNotFound, "Not found")
+			return
+		}
+		c.Next()
+	}
+}
+
+// adminOnly lets through only callers listed in admin.users. It runs after
+// authMiddleware; without authentication there is no caller to match, so
+// with auth_mode none every request is refused.
+func adminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		for _, admin := range config().Admin.Users {
+			if user != "" && user == admin {
+				c.Next()
+				return
+			}
+		}
+		respondError(c, http.StatusForbidden, errCodeForbidden, "admin access required")
+	}
+}
+
+func generateMaxCount() int {
+	if config().Admin.GenerateMaxCount > 0 {
+		return config().Admin.GenerateMaxCount
+	}
+	return defaultGenerateMaxCount
+}
+
+func generateBatchSize() int {
+	if config().Admin.GenerateBatchSize > 0 {
+		return config().Admin.GenerateBatchSize
+	}
+	return defaultGenerateBatchSize
+}
+
+// insertSyntheticBatch inserts n randomized tasks on behalf of user in a
+// single transaction. Like insertTask, user owns them, so they show up in
+// the caller's own lists and searches.
+func insertSyntheticBatch(ctx context.Context, rng *rand.Rand, n int, user string) error {
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO tasks (title, description, status, priority, created_by, updated_by, owner_id, updated_at, completed_at) VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CASE WHEN ? = 'completed' THEN CURRENT_TIMESTAMP END)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	owner := nullableString(user)
+	for i := 0; i < n; i++ {
+		verb := sampleVerbs[rng.Intn(len(sampleVerbs))]
+		subject := sampleSubjects[rng.Intn(len(sampleSubjects))]
+		title := fmt.Sprintf("%s %s #%d", verb, subject, rng.Intn(1000000))
+		description := fmt.Sprintf("Synthetic task: %s the %s", verb, subject)
+		status := sampleStatuses[rng.Intn(len(sampleStatuses))]
+		priority := samplePriorities[rng.Intn(len(samplePriorities))]
+
+		if _, err := stmt.ExecContext(ctx, title, description, status, priority, owner, owner, owner, status); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// generateTasks bulk-inserts synthetic tasks for load testing, streaming
+// progress as newline-delimited JSON.
+func generateTasks(c *gin.Context) {
+	count, err := strconv.Atoi(c.Query("count"))
+	if err != nil || count <= 0 {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "count must be a positive integer")
+		return
+	}
+	if limit := generateMaxCount(); count > limit {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("count must not exceed %d", limit))
+		return
+	}
+
+	batchSize := generateBatchSize()
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	start := time.Now()
+	inserted := 0
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+
+	for inserted < count {
+		n := count - inserted
+		if n > batchSize {
+			n = batchSize
+		}
+
+		if err := insertSyntheticBatch(c.Request.Context(), rng, n, currentUser(c)); err != nil {
+			encoder.Encode(GenerateProgress{Inserted: inserted, Requested: count, Done: true, Error: err.Error()})
+			return
+		}
+		inserted += n
+
+		if inserted < count {
+			encoder.Encode(GenerateProgress{Inserted: inserted, Requested: count})
+			c.Writer.Flush()
+			if c.Request.Context().Err() != nil {
+				return
+			}
+		}
+	}
+
+	elapsed := time.Since(start)
+	encoder.Encode(GenerateProgress{
+		Inserted:       inserted,
+		Requested:      count,
+		Done:           true,
+		DurationMS:     elapsed.Milliseconds(),
+		TasksPerSecond: float64(inserted) / elapsed.Seconds(),
+	})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testAdminKey = "admin-key"
+	testUserKey  = "user-key"
+)
+
+// useTestAdmin turns on API key auth with testAdminKey as the only admin.
+func useTestAdmin() {
+	config().Security.AuthMode = authModeAPIKey
+	config().Security.APIKeys = []string{testAdminKey, testUserKey}
+	config().Admin.Users = []string{apiKeyID(0)}
+}
+
+func generateRequest(query, key string) *http.Request {
+	req, _ := http.NewRequest("POST", "/api/v1/admin/generate"+query, nil)
+	if key != "" {
+		req.Header.Set(apiKeyHeader, key)
+	}
+	return req
+}
+
+func countStoredTasks(t *testing.T) int {
+	t.Helper()
+	var n int
+	assert.NoError(t, db().QueryRow("SELECT COUNT(*) FROM tasks").Scan(&n))
+	return n
+}
+
+func TestGenerateTasks(t *testing.T) {
+	router := setupTestRouter()
+	useTestAdmin()
+	config().Admin.GenerateBatchSize = 4
+	before := countStoredTasks(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, generateRequest("?count=10", testAdminKey))
+
+	assert.Equal(t, 200, w.Code)
+
+	var lines []GenerateProgress
+	scanner := bufio.NewScanner(strings.NewReader(w.Body.String()))
+	for scanner.Scan() {
+		var p GenerateProgress
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &p))
+		lines = append(lines, p)
+	}
+
+	assert.Len(t, lines, 3)
+	assert.Equal(t, 4, lines[0].Inserted)
+	last := lines[len(lines)-1]
+	assert.True(t, last.Done)
+	assert.Equal(t, 10, last.Inserted)
+	assert.Empty(t, last.Error)
+
+	assert.Equal(t, before+10, countStoredTasks(t))
+}
+
+func TestGenerateTasksOwnedByCaller(t *testing.T) {
+	router := setupTestRouter()
+	useTestAdmin()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, generateRequest("?count=3", testAdminKey))
+	assert.Equal(t, 200, w.Code)
+
+	list := func(key string) []Task {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/tasks?q=Synthetic", nil)
+		req.Header.Set(apiKeyHeader, key)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code, w.Body.String())
+		var tasks []Task
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+		return tasks
+	}
+
+	tasks := list(testAdminKey)
+	assert.Len(t, tasks, 3)
+	for _, task := range tasks {
+		assert.Equal(t, apiKeyID(0), *task.OwnerID)
+		assert.Equal(t, apiKeyID(0), *task.CreatedBy)
+		assert.Equal(t, apiKeyID(0), *task.UpdatedBy)
+	}
+	assert.Empty(t, list(testUserKey), "other callers don't see them")
+}
+
+func TestGenerateTasksRequiresAdmin(t *testing.T) {
+	router := setupTestRouter()
+
+	// With auth_mode none there is no caller to recognise as an admin.
+	config().Admin.Users = []string{""}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, generateRequest("?count=1", ""))
+	assert.Equal(t, 403, w.Code)
+
+	useTestAdmin()
+	before := countStoredTasks(t)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, generateRequest("?count=1", ""))
+	assert.Equal(t, 401, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, generateRequest("?count=1", testUserKey))
+	assert.Equal(t, 403, w.Code)
+	assert.Contains(t, w.Body.String(), errCodeForbidden)
+
+	assert.Equal(t, before, countStoredTasks(t), "nothing was generated")
+}
+
+func TestGenerateTasksValidation(t *testing.T) {
+	router := setupTestRouter()
+	useTestAdmin()
+	config().Admin.GenerateMaxCount = 50
+
+	for _, query := range []string{"", "?count=0", "?count=abc", "?count=51"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, generateRequest(query, testAdminKey))
+		assert.Equal(t, 400, w.Code, query)
+	}
+}
+
+func TestGenerateTasksHiddenInProduction(t *testing.T) {
+	router := setupTestRouter()
+	config().App.Environment = "production"
+
+	useTestAdmin()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, generateRequest("?count=1", testAdminKey))
+
+	assert.Equal(t, 404, w.Code)
+}
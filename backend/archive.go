@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// archiveTask hides a task from the default listings without deleting it;
+// GET /tasks?archived=true still shows it.
+func archiveTask(c *gin.Context) {
+	setArchived(c, true)
+}
+
+func unarchiveTask(c *gin.Context) {
+	setArchived(c, false)
+}
+
+// setArchived sets the archived flag of a live task. Repeating the call is
+// harmless, so archiving an archived task succeeds.
+func setArchived(c *gin.Context, archived bool) {
+	ctx := c.Request.Context()
+	id, ok := taskIDParam(c)
+	if !ok {
+		return
+	}
+
+	scope, args := ownerScope(c)
+	args = append([]interface{}{archived, nullableString(currentUser(c)), id}, args...)
+	tasks, err := queryTasks(ctx, db(), "UPDATE tasks SET archived = ?, updated_by = ?, updated_at = CURRENT_TIMESTAMP, version = version + 1 WHERE id = ? AND "+notDeletedPredicate+scope+" RETURNING "+taskColumns, args...)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	if len(tasks) == 0 {
+		respondError(c, http.StatusNotFound, errCodeNotFound, "Task not found")
+		return
+	}
+	publishTaskEvent(eventTaskUpdated, tasks[0])
+
+	c.JSON(http.StatusOK, tasks[0])
+}
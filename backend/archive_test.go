@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func postTestArchive(t *testing.T, router *gin.Engine, id int, action string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/"+strconv.Itoa(id)+"/"+action, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestArchiveTask(t *testing.T) {
+	router := setupTestRouter()
+	task := createTestTask(t, router, Task{Title: "Old news"})
+
+	w := postTestArchive(t, router, task.ID, "archive")
+	assert.Equal(t, 200, w.Code)
+	var archived Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &archived))
+	assert.True(t, archived.Archived)
+	assert.Equal(t, task.Version+1, archived.Version)
+	assert.Equal(t, 200, postTestArchive(t, router, task.ID, "archive").Code, "archiving twice is harmless")
+
+	assert.Empty(t, listTestTasks(t, router, "?q=Old+news"), "archived tasks are hidden by default")
+	assert.Len(t, listTestTasks(t, router, "?q=Old+news&archived=true"), 1)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/"+strconv.Itoa(task.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code, "archived tasks are still readable by id")
+
+	w = postTestArchive(t, router, task.ID, "unarchive")
+	assert.Equal(t, 200, w.Code)
+	assert.Len(t, listTestTasks(t, router, "?q=Old+news"), 1)
+}
+
+func TestArchiveTaskNotFound(t *testing.T) {
+	router := setupTestRouter()
+	task := createTestTask(t, router, Task{Title: "Gone"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/tasks/"+strconv.Itoa(task.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	assert.Equal(t, 404, postTestArchive(t, router, task.ID, "archive").Code)
+	assert.Equal(t, 404, postTestArchive(t, router, 99999, "unarchive").Code)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// userIDKey is the gin context key holding the authenticated user's id.
+	userIDKey = "user_id"
+	// claimsKey is the gin context key holding the parsed JWT claims.
+	claimsKey = "claims"
+)
+
+const (
+	authModeNone   = "none"
+	authModeJWT    = "jwt"
+	authModeAPIKey = "api_key"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+const defaultTokenTTLMinutes = 60
+
+// dummyPasswordHash is compared against when a username doesn't exist so a
+// failed login takes the same time whether or not the user is real.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("taskhub-dummy-password"), bcrypt.DefaultCost)
+
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type LoginResponse struct {
+	Token     string `json:"token"`
+	TokenType string `json:"token_type"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// Claims are the JWT claims issued to users; the subject is the username,
+// which serves as the user id throughout the API.
+type Claims struct {
+	jwt.RegisteredClaims
+}
+
+// currentUser returns the authenticated user's id, or "" for anonymous
+// requests.
+func currentUser(c *gin.Context) string {
+	return c.GetString(userIDKey)
+}
+
+// authMiddleware protects a route group with the mechanism selected by
+// security.auth_mode. The mode is read per request so tests and config
+// changes take effect without rebuilding the router.
+func authMiddleware() gin.HandlerFunc {
+	jwtAuth := jwtAuthMiddleware()
+	apiKeyAuth := apiKeyMiddleware()
+	return func(c *gin.Context) {
+		switch config().Security.AuthMode {
+		case "", authModeNone:
+			c.Next()
+		case authModeJWT:
+			jwtAuth(c)
+		case authModeAPIKey:
+			apiKeyAuth(c)
+		default:
+			respondError(c, http.StatusInternalServerError, errCodeInternal, "unsupported auth mode")
+		}
+	}
+}
+
+// jwtAuthMiddleware requires a valid HS256 bearer token signed with
+// security.jwt_secret and exposes its claims on the context.
+func jwtAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			c.Header("WWW-Authenticate", "Bearer")
+			respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "missing bearer token")
+			return
+		}
+
+		claims, err := parseToken(strings.TrimSpace(token))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "invalid or expired token")
+			return
+		}
+
+		c.Set(claimsKey, claims)
+		c.Set(userIDKey, claims.Subject)
+		c.Next()
+	}
+}
+
+// apiKeyMiddleware requires an X-API-Key header matching one of
+// security.api_keys. Callers are identified as "api-key-N" after the index of
+// the matching key so their writes can still be attributed.
+func apiKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(apiKeyHeader)
+		if key == "" {
+			respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "missing API key")
+			return
+		}
+
+		index := matchAPIKey(key, config().Security.APIKeys)
+		if index < 0 {
+			respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "invalid API key")
+			return
+		}
+
+		c.Set(userIDKey, apiKeyID(index))
+		c.Next()
+	}
+}
+
+// apiKeyID names the API key at index in security.api_keys; it is the user
+// id of requests made with it.
+func apiKeyID(index int) string {
+	return fmt.Sprintf("api-key-%d", index+1)
+}
+
+// matchAPIKey returns the index of key in keys, or -1. Keys are hashed to a
+// fixed length and every entry is compared in constant time, so the response
+// time reveals neither which key matched nor how much of a key was right.
+func matchAPIKey(key string, keys []string) int {
+	got := sha256.Sum256([]byte(key))
+	match := -1
+	for i, candidate := range keys {
+		if candidate == "" {
+			continue
+		}
+		want := sha256.Sum256([]byte(candidate))
+		if subtle.ConstantTimeCompare(got[:], want[:]) == 1 && match < 0 {
+			match = i
+		}
+	}
+	return match
+}
+
+// parseToken verifies the signature and standard time claims of a token.
+func parseToken(token string) (*Claims, error) {
+	secret := config().Security.JWTSecret
+	if secret == "" {
+		return nil, errors.New("jwt secret is not configured")
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return claims, nil
+}
+
+func tokenTTL() time.Duration {
+	if config().Security.TokenTTLMinutes > 0 {
+		return time.Duration(config().Security.TokenTTLMinutes) * time.Minute
+	}
+	return defaultTokenTTLMinutes * time.Minute
+}
+
+// issueToken signs a token for the given user that expires after tokenTTL.
+func issueToken(username string) (string, time.Time, error) {
+	secret := config().Security.JWTSecret
+	if secret == "" {
+		return "", time.Time{}, errors.New("jwt secret is not configured")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(tokenTTL())
+	claims := Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   username,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	return token, expiresAt, err
+}
+
+// createUser stores a user with a bcrypt hash of the password.
+func createUser(ctx context.Context, username, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	_, err = db().ExecContext(ctx, "INSERT INTO users (username, password_hash) VALUES (?, ?)", username, string(hash))
+	return err
+}
+
+// login exchanges a username and password for a signed JWT. Every failure
+// returns the same generic 401 so callers can't probe which usernames exist.
+func login(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	var hash string
+	err := db().QueryRowContext(ctx, "SELECT password_hash FROM users WHERE username = ?", req.Username).Scan(&hash)
+	if err != nil && err != sql.ErrNoRows {
+		respondInternalError(c, err)
+		return
+	}
+
+	if err == sql.ErrNoRows {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(req.Password))
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "invalid username or password")
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)) != nil {
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "invalid username or password")
+		return
+	}
+
+	token, expiresAt, err := issueToken(req.Username)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginResponse{
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+	})
+}
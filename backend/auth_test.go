@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const testJWTSecret = "test-secret"
+
+func signTestToken(t *testing.T, secret, subject string, ttl time.Duration) string {
+	t.Helper()
+
+	claims := Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   subject,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+	}}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	assert.NoError(t, err)
+	return token
+}
+
+func setupJWTTestRouter() *gin.Engine {
+	router := setupTestRouter()
+	config().Security.AuthMode = authModeJWT
+	config().Security.JWTSecret = testJWTSecret
+	return router
+}
+
+func TestJWTAuthRejectsMissingAndInvalidTokens(t *testing.T) {
+	router := setupJWTTestRouter()
+
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.RegisteredClaims{Subject: "mallory"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	for name, header := range map[string]string{
+		"missing":      "",
+		"not bearer":   "Basic dXNlcjpwYXNz",
+		"garbage":      "Bearer not-a-jwt",
+		"wrong secret": "Bearer " + signTestToken(t, "other-secret", "alice", time.Hour),
+		"expired":      "Bearer " + signTestToken(t, testJWTSecret, "alice", -time.Minute),
+		"alg none":     "Bearer " + unsigned,
+		"no subject":   "Bearer " + signTestToken(t, testJWTSecret, "", time.Hour),
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/tasks", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 401, w.Code, name)
+	}
+}
+
+func TestJWTAuthAcceptsValidToken(t *testing.T) {
+	router := setupJWTTestRouter()
+	token := signTestToken(t, testJWTSecret, "alice", time.Hour)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewBufferString(`{"title":"Mine"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 201, w.Code)
+	var created Task
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if assert.NotNil(t, created.CreatedBy) {
+		assert.Equal(t, "alice", *created.CreatedBy)
+	}
+}
+
+func TestTasksAreScopedToOwner(t *testing.T) {
+	router := setupJWTTestRouter()
+	alice := "Bearer " + signTestToken(t, testJWTSecret, "alice", time.Hour)
+	bob := "Bearer " + signTestToken(t, testJWTSecret, "bob", time.Hour)
+
+	send := func(method, path, auth, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", auth)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/api/v1/tasks", alice, `{"title":"Alice only"}`)
+	assert.Equal(t, 201, w.Code)
+	var created Task
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if assert.NotNil(t, created.OwnerID) {
+		assert.Equal(t, "alice", *created.OwnerID)
+	}
+	path := "/api/v1/tasks/" + strconv.Itoa(created.ID)
+
+	var tasks []Task
+	w = send("GET", "/api/v1/tasks", alice, "")
+	json.Unmarshal(w.Body.Bytes(), &tasks)
+	assert.Len(t, tasks, 1)
+
+	w = send("GET", "/api/v1/tasks", bob, "")
+	tasks = nil
+	json.Unmarshal(w.Body.Bytes(), &tasks)
+	assert.Empty(t, tasks)
+
+	assert.Equal(t, 404, send("GET", path, bob, "").Code)
+	assert.Equal(t, 404, send("PUT", path, bob, `{"title":"Hijacked","version":1}`).Code)
+	assert.Equal(t, 404, send("DELETE", path, bob, "").Code)
+
+	assert.Equal(t, 200, send("GET", path, alice, "").Code)
+	assert.Equal(t, 200, send("DELETE", path, alice, "").Code)
+}
+
+func TestJWTAuthLeavesHealthPublic(t *testing.T) {
+	router := setupJWTTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/health", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestLogin(t *testing.T) {
+	router := setupJWTTestRouter()
+	assert.NoError(t, createUser(context.Background(), "alice", "correct horse"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBufferString(`{"username":"alice","password":"correct horse"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	var response LoginResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Bearer", response.TokenType)
+	expiresAt, err := time.Parse(time.RFC3339, response.ExpiresAt)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+
+	// The issued token opens the protected API
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks", nil)
+	req.Header.Set("Authorization", "Bearer "+response.Token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestLoginFailuresAreGeneric(t *testing.T) {
+	router := setupJWTTestRouter()
+	assert.NoError(t, createUser(context.Background(), "alice", "correct horse"))
+
+	var bodies []string
+	for _, creds := range []string{
+		`{"username":"alice","password":"wrong"}`,
+		`{"username":"nobody","password":"wrong"}`,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBufferString(creds))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 401, w.Code)
+		bodies = append(bodies, w.Body.String())
+	}
+	assert.Equal(t, bodies[0], bodies[1])
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBufferString(`{"username":"alice"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestLoginTokenTTLFromConfig(t *testing.T) {
+	setupJWTTestRouter()
+	config().Security.TokenTTLMinutes = 5
+
+	_, expiresAt, err := issueToken("alice")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), expiresAt, time.Minute)
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	router := setupTestRouter()
+	config().Security.AuthMode = authModeAPIKey
+	config().Security.APIKeys = []string{"first-key", "second-key"}
+
+	for key, want := range map[string]int{
+		"":           401,
+		"wrong-key":  401,
+		"first-key":  200,
+		"second-key": 200,
+		"second-ke":  401,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/tasks", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		router.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, key)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewBufferString(`{"title":"From a service"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "second-key")
+	router.ServeHTTP(w, req)
+
+	var created Task
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if assert.NotNil(t, created.CreatedBy) {
+		assert.Equal(t, "api-key-2", *created.CreatedBy)
+	}
+}
+
+func TestMatchAPIKey(t *testing.T) {
+	assert.Equal(t, -1, matchAPIKey("x", nil))
+	assert.Equal(t, -1, matchAPIKey("", []string{""}))
+	assert.Equal(t, 1, matchAPIKey("b", []string{"a", "b", "b"}))
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	batchOpCreate = "create"
+	batchOpUpdate = "update"
+	batchOpDelete = "delete"
+)
+
+// BatchOperation is one step of POST /tasks/batch-ops. Data holds the task
+// fields for create and update, as POST and PUT /tasks take them; an update
+// must also carry the version it is based on in data.version.
+type BatchOperation struct {
+	Op   string          `json:"op"`
+	ID   int             `json:"id"`
+	Data json.RawMessage `json:"data"`
+}
+
+// BatchOperationResult reports one applied operation: the task as created
+// or updated, or as it was when deleted.
+type BatchOperationResult struct {
+	Index int    `json:"index"`
+	Op    string `json:"op"`
+	ID    int    `json:"id"`
+	Task  Task   `json:"task"`
+}
+
+// batchTaskOperations applies a mix of creates, updates and deletes in order
+// within one transaction, so an offline client can sync its queued changes
+// in a single request. The first operation that fails rolls back the whole
+// batch and is reported with its index; the others are never applied.
+func batchTaskOperations(c *gin.Context) {
+	ctx := c.Request.Context()
+	var ops []BatchOperation
+	if err := c.ShouldBindJSON(&ops); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if len(ops) == 0 {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "batch must contain at least one operation")
+		return
+	}
+	if len(ops) > maxBatchSize {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("batch must not exceed %d operations", maxBatchSize))
+		return
+	}
+
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer tx.Rollback()
+
+	results := make([]BatchOperationResult, 0, len(ops))
+	events := make([]string, 0, len(ops))
+	for i, op := range ops {
+		op.Op = strings.ToLower(strings.TrimSpace(op.Op))
+		task, event, err := applyBatchOperation(c, tx, op)
+		if err != nil {
+			respondBatchOperationError(c, i, err)
+			return
+		}
+		results = append(results, BatchOperationResult{Index: i, Op: op.Op, ID: task.ID, Task: task})
+		events = append(events, event)
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	for i, result := range results {
+		publishTaskEvent(events[i], result.Task)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// applyBatchOperation runs one operation within tx through the same write
+// paths as the single-task endpoints, returning the task and the event to
+// publish once the batch commits.
+func applyBatchOperation(c *gin.Context, tx dbtx, op BatchOperation) (Task, string, error) {
+	switch op.Op {
+	case batchOpCreate:
+		task, err := batchOperationTask(op)
+		if err != nil {
+			return Task{}, "", err
+		}
+		if task.Status == "" {
+			task.Status = "pending"
+		}
+		if err := storeNewTask(c, tx, &task); err != nil {
+			return Task{}, "", err
+		}
+		return task, eventTaskCreated, nil
+	case batchOpUpdate:
+		if op.ID <= 0 {
+			return Task{}, "", newValidationError(msgInvalidID)
+		}
+		task, err := batchOperationTask(op)
+		if err != nil {
+			return Task{}, "", err
+		}
+		if task.Version <= 0 {
+			return Task{}, "", &requestError{status: http.StatusPreconditionRequired, code: errCodePreconditionRequired, message: "data.version is required"}
+		}
+		updated, err := writeTaskUpdate(c, tx, op.ID, task.Version, task)
+		return updated, eventTaskUpdated, err
+	case batchOpDelete:
+		if op.ID <= 0 {
+			return Task{}, "", newValidationError(msgInvalidID)
+		}
+		deleted, err := softDeleteTask(c, tx, op.ID)
+		return deleted, eventTaskDeleted, err
+	default:
+		return Task{}, "", &requestError{status: http.StatusBadRequest, code: errCodeInvalidRequest, message: fmt.Sprintf("op must be one of create, update, delete, not %q", op.Op)}
+	}
+}
+
+// batchOperationTask decodes and validates the data of a create or update.
+func batchOperationTask(op BatchOperation) (Task, error) {
+	var task Task
+	if len(op.Data) == 0 || string(op.Data) == "null" {
+		return Task{}, &requestError{status: http.StatusBadRequest, code: errCodeInvalidRequest, message: "data is required"}
+	}
+	if err := json.Unmarshal(op.Data, &task); err != nil {
+		return Task{}, &requestError{status: http.StatusBadRequest, code: errCodeInvalidRequest, message: "invalid data: " + err.Error()}
+	}
+	return task, validateTask(&task)
+}
+
+// respondBatchOperationError answers as respondWriteError would, prefixing
+// the message with the failing operation and adding its index to details.
+func respondBatchOperationError(c *gin.Context, index int, err error) {
+	details := gin.H{"index": index}
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		if extra, ok := reqErr.details.(gin.H); ok {
+			for key, value := range extra {
+				details[key] = value
+			}
+		}
+		respondErrorWith(c, reqErr.status, reqErr.code, fmt.Sprintf("operation %d: %s", index, reqErr.message), details)
+	case isValidationError(err):
+		respondErrorWith(c, http.StatusBadRequest, errCodeValidation, fmt.Sprintf("operation %d: %s", index, localize(c, err)), details)
+	default:
+		respondInternalError(c, fmt.Errorf("operation %d: %w", index, err))
+	}
+}
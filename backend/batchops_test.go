@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func postBatchOperations(t *testing.T, router *gin.Engine, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/batch-ops", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestBatchTaskOperations(t *testing.T) {
+	router := setupTestRouter()
+	edited := createTestTask(t, router, Task{Title: "Edit me"})
+	doomed := createTestTask(t, router, Task{Title: "Delete me"})
+
+	w := postBatchOperations(t, router, fmt.Sprintf(`[
+		{"op":"create","data":{"title":"Made offline","priority":"high"}},
+		{"op":"update","id":%d,"data":{"title":"Edited offline","status":"in_progress","version":%d}},
+		{"op":"DELETE","id":%d}
+	]`, edited.ID, edited.Version, doomed.ID))
+	assert.Equal(t, 200, w.Code, w.Body.String())
+
+	var response struct {
+		Results []BatchOperationResult `json:"results"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	if assert.Len(t, response.Results, 3) {
+		created := response.Results[0]
+		assert.Equal(t, batchOpCreate, created.Op)
+		assert.Equal(t, "pending", created.Task.Status)
+		assert.Equal(t, "Made offline", getTestTask(t, router, created.ID).Title)
+
+		assert.Equal(t, 1, response.Results[1].Index)
+		assert.Equal(t, edited.ID, response.Results[1].ID)
+		assert.Equal(t, "Edited offline", response.Results[1].Task.Title)
+		assert.Equal(t, edited.Version+1, response.Results[1].Task.Version)
+
+		assert.Equal(t, batchOpDelete, response.Results[2].Op)
+		assert.Equal(t, "Delete me", response.Results[2].Task.Title)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/tasks/%d", doomed.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestBatchTaskOperationsRollBack(t *testing.T) {
+	router := setupTestRouter()
+	task := createTestTask(t, router, Task{Title: "Stale"})
+	before := countTestTasks(t, router, "")
+
+	// The update targets the task deleted just before it, so it fails and
+	// the whole batch, including the create and the delete, is undone.
+	w := postBatchOperations(t, router, fmt.Sprintf(`[
+		{"op":"create","data":{"title":"Never stored"}},
+		{"op":"delete","id":%d},
+		{"op":"update","id":%d,"data":{"title":"Too late","version":%d}}
+	]`, task.ID, task.ID, task.Version+5))
+	assert.Equal(t, 404, w.Code)
+	var response APIError
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, errCodeNotFound, response.Code)
+	assert.Equal(t, map[string]interface{}{"index": float64(2)}, response.Details)
+	assert.Equal(t, before, countTestTasks(t, router, ""))
+	assert.Equal(t, "Stale", getTestTask(t, router, task.ID).Title)
+
+	w = postBatchOperations(t, router, fmt.Sprintf(`[{"op":"update","id":%d,"data":{"title":"Too late","version":%d}}]`, task.ID, task.Version+5))
+	assert.Equal(t, 409, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, map[string]interface{}{"index": float64(0), "current_version": float64(task.Version)}, response.Details)
+
+	for body, status := range map[string]int{
+		`[]`:                                    400,
+		`[{"op":"rename","id":1}]`:              400,
+		`[{"op":"create"}]`:                     400,
+		`[{"op":"create","data":{"title":""}}]`: 400,
+		`[{"op":"delete"}]`:                     400,
+		fmt.Sprintf(`[{"op":"update","id":%d,"data":{"title":"x"}}]`, task.ID): 428,
+	} {
+		w := postBatchOperations(t, router, body)
+		assert.Equal(t, status, w.Code, body)
+	}
+	assert.Equal(t, before, countTestTasks(t, router, ""))
+}
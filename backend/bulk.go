@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBatchSize caps how many tasks a single bulk request may touch.
+const maxBatchSize = 500
+
+// BulkIDsRequest is the body shared by the bulk endpoints that act on ids.
+type BulkIDsRequest struct {
+	IDs []int `json:"ids"`
+}
+
+// queryTasks runs a query returning taskColumns, such as an UPDATE with
+// RETURNING, and scans every row.
+func queryTasks(ctx context.Context, q dbtx, query string, args ...interface{}) ([]Task, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// validateBulkIDs checks the id list is non-empty and within maxBatchSize.
+func validateBulkIDs(ids []int) error {
+	if len(ids) == 0 {
+		return newValidationError(msgIDsEmpty)
+	}
+	if len(ids) > maxBatchSize {
+		return newValidationError(msgIDsTooMany, maxBatchSize)
+	}
+	return nil
+}
+
+// inClause returns a parameterized "IN (?, ?, ...)" clause and its arguments.
+func inClause(ids []int) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return placeholderList(args), args
+}
+
+// stringInClause is inClause for string values.
+func stringInClause(values []string) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return placeholderList(args), args
+}
+
+func placeholderList(args []interface{}) string {
+	placeholders := make([]string, len(args))
+	for i := range args {
+		placeholders[i] = "?"
+	}
+	return "IN (" + strings.Join(placeholders, ", ") + ")"
+}
+
+// bulkDeleteTasks soft-deletes every listed task, like deleteTask, and reports
+// how many were actually deleted. Unknown or already deleted ids are skipped.
+// With ?dry_run=true nothing is deleted; the tasks that would be are returned
+// instead.
+func bulkDeleteTasks(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req BulkIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := validateBulkIDs(req.IDs); err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+
+	in, args := inClause(req.IDs)
+	scope, scopeArgs := ownerScope(c)
+	if c.Query("dry_run") == "true" {
+		matched, err := queryTasks(ctx, db(), "SELECT "+taskColumns+" FROM tasks WHERE id "+in+" AND "+notDeletedPredicate+scope+" ORDER BY id", append(args, scopeArgs...)...)
+		if err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		if matched == nil {
+			matched = []Task{}
+		}
+		c.JSON(http.StatusOK, gin.H{"dry_run": true, "would_delete": len(matched), "tasks": matched})
+		return
+	}
+
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer tx.Rollback()
+
+	deleted, err := queryTasks(ctx, tx, "UPDATE tasks SET deleted_at = CURRENT_TIMESTAMP WHERE id "+in+" AND "+notDeletedPredicate+scope+" RETURNING "+taskColumns, append(args, scopeArgs...)...)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	for _, task := range deleted {
+		publishTaskEvent(eventTaskDeleted, task)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": len(deleted)})
+}
+
+type BulkStatusRequest struct {
+	IDs    []int  `json:"ids"`
+	Status string `json:"status"`
+}
+
+// bulkUpdateStatus moves every listed task to the same status in one UPDATE
+// and reports how many rows changed. Tasks that statusTransitions doesn't
+// allow to move to the status are skipped, like unknown ids.
+func bulkUpdateStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req BulkStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	status := normalizeStatus(req.Status)
+	if !validStatuses[status] {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, newValidationError(msgStatusInvalid)))
+		return
+	}
+	if err := validateBulkIDs(req.IDs); err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer tx.Rollback()
+
+	in, args := inClause(req.IDs)
+	from, fromArgs := stringInClause(statusesLeadingTo(status))
+	scope, scopeArgs := ownerScope(c)
+	args = append([]interface{}{status, status, nullableString(currentUser(c))}, args...)
+	args = append(args, fromArgs...)
+	updated, err := queryTasks(ctx, tx, "UPDATE tasks SET status = ?, "+completedAtAssignment+", updated_by = ?, updated_at = CURRENT_TIMESTAMP, version = version + 1 WHERE id "+in+" AND status "+from+" AND "+notDeletedPredicate+scope+" RETURNING "+taskColumns, append(args, scopeArgs...)...)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	for _, task := range updated {
+		publishTaskEvent(eventTaskUpdated, task)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": len(updated)})
+}
+
+// createTasksBatch inserts a JSON array of tasks atomically: either every
+// task is created, in order, or none are.
+func createTasksBatch(c *gin.Context) {
+	ctx := c.Request.Context()
+	var tasks []Task
+	if err := c.ShouldBindJSON(&tasks); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if len(tasks) == 0 {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "batch must contain at least one task")
+		return
+	}
+	if len(tasks) > maxBatchSize {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("batch must not exceed %d tasks", maxBatchSize))
+		return
+	}
+
+	for i := range tasks {
+		if err := validateTask(&tasks[i]); err != nil {
+			respondErrorWith(c, http.StatusBadRequest, errCodeValidation, fmt.Sprintf("task %d: %s", i, localize(c, err)), gin.H{"index": i})
+			return
+		}
+		if tasks[i].Status == "" {
+			tasks[i].Status = "pending"
+		}
+	}
+
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer tx.Rollback()
+
+	user := currentUser(c)
+	for i := range tasks {
+		if err := checkParent(c, tx, 0, tasks[i].ParentID); err != nil {
+			if isValidationError(err) {
+				respondErrorWith(c, http.StatusBadRequest, errCodeValidation, fmt.Sprintf("task %d: %s", i, localize(c, err)), gin.H{"index": i})
+			} else {
+				respondInternalError(c, err)
+			}
+			return
+		}
+		if config().App.TitleAutoSuffix {
+			title, err := nextAvailableTitle(ctx, tx, tasks[i].Title)
+			if err != nil {
+				respondInternalError(c, err)
+				return
+			}
+			tasks[i].Title = title
+		}
+
+		if err := insertTask(ctx, tx, &tasks[i], user); err != nil {
+			respondInternalError(c, fmt.Errorf("task %d: %w", i, err))
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	for _, task := range tasks {
+		publishTaskEvent(eventTaskCreated, task)
+	}
+
+	c.JSON(http.StatusCreated, tasks)
+}
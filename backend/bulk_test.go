@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTasksBatch(t *testing.T) {
+	router := setupTestRouter()
+	before := len(listTestTasks(t, router, ""))
+
+	body := `[{"title":"First"},{"title":"Second","status":"in_progress"},{"title":"Third","priority":"high"}]`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/batch", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 201, w.Code)
+	var created []Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	if assert.Len(t, created, 3) {
+		assert.Equal(t, "First", created[0].Title)
+		assert.Equal(t, "Third", created[2].Title)
+		assert.Less(t, created[0].ID, created[1].ID)
+		assert.Less(t, created[1].ID, created[2].ID)
+		assert.Equal(t, "pending", created[0].Status)
+	}
+
+	assert.Len(t, listTestTasks(t, router, ""), before+3)
+}
+
+func TestCreateTasksBatchRejectsInvalidTask(t *testing.T) {
+	router := setupTestRouter()
+	before := len(listTestTasks(t, router, ""))
+
+	body := `[{"title":"Fine"},{"title":"Also fine"},{"title":"  "}]`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/batch", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	var response APIError
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, errCodeValidation, response.Code)
+	assert.Equal(t, map[string]interface{}{"index": float64(2)}, response.Details)
+
+	// Nothing from the batch was stored
+	assert.Len(t, listTestTasks(t, router, ""), before)
+}
+
+func TestCreateTasksBatchSizeLimits(t *testing.T) {
+	router := setupTestRouter()
+
+	tooMany := make([]Task, maxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i].Title = "x"
+	}
+	oversized, _ := json.Marshal(tooMany)
+
+	for _, body := range [][]byte{[]byte(`[]`), oversized} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/tasks/batch", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code)
+	}
+}
+
+func TestBulkDeleteTasks(t *testing.T) {
+	router := setupTestRouter()
+
+	a := createTestTask(t, router, Task{Title: "Bulk A"})
+	b := createTestTask(t, router, Task{Title: "Bulk B"})
+	keep := createTestTask(t, router, Task{Title: "Keep"})
+
+	body, _ := json.Marshal(BulkIDsRequest{IDs: []int{a.ID, b.ID, 99999}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/bulk-delete", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"deleted":2}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks/"+strconv.Itoa(a.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks/"+strconv.Itoa(keep.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	// Repeating the request deletes nothing new
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/tasks/bulk-delete", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"deleted":0}`, w.Body.String())
+}
+
+func TestBulkDeleteTasksDryRun(t *testing.T) {
+	router := setupTestRouter()
+
+	a := createTestTask(t, router, Task{Title: "Dry A"})
+	b := createTestTask(t, router, Task{Title: "Dry B"})
+
+	body, _ := json.Marshal(BulkIDsRequest{IDs: []int{b.ID, a.ID, 99999}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/bulk-delete?dry_run=true", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	var response struct {
+		DryRun      bool   `json:"dry_run"`
+		WouldDelete int    `json:"would_delete"`
+		Tasks       []Task `json:"tasks"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.DryRun)
+	assert.Equal(t, 2, response.WouldDelete)
+	if assert.Len(t, response.Tasks, 2) {
+		assert.Equal(t, a.ID, response.Tasks[0].ID)
+		assert.Equal(t, "Dry B", response.Tasks[1].Title)
+	}
+
+	// Nothing was deleted
+	getTestTask(t, router, a.ID)
+	getTestTask(t, router, b.ID)
+
+	body, _ = json.Marshal(BulkIDsRequest{IDs: []int{99999}})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/tasks/bulk-delete?dry_run=true", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"dry_run":true,"would_delete":0,"tasks":[]}`, w.Body.String())
+}
+
+func TestBulkDeleteTasksValidation(t *testing.T) {
+	router := setupTestRouter()
+
+	oversized, _ := json.Marshal(BulkIDsRequest{IDs: make([]int, maxBatchSize+1)})
+	for _, body := range [][]byte{[]byte(`{"ids":[]}`), []byte(`{}`), oversized} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/tasks/bulk-delete", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code)
+	}
+}
+
+func TestBulkUpdateStatus(t *testing.T) {
+	router := setupTestRouter()
+
+	a := createTestTask(t, router, Task{Title: "Close A"})
+	b := createTestTask(t, router, Task{Title: "Close B"})
+
+	w := httptest.NewRecorder()
+	body := `{"ids":[` + strconv.Itoa(a.ID) + `,` + strconv.Itoa(b.ID) + `,99999],"status":"Completed"}`
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/bulk-status", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"updated":2}`, w.Body.String())
+
+	completed := map[int]bool{}
+	for _, task := range listTestTasks(t, router, "?status=completed") {
+		completed[task.ID] = true
+	}
+	assert.True(t, completed[a.ID])
+	assert.True(t, completed[b.ID])
+}
+
+func TestBulkUpdateStatusInvalidStatus(t *testing.T) {
+	router := setupTestRouter()
+
+	a := createTestTask(t, router, Task{Title: "Untouched"})
+
+	w := httptest.NewRecorder()
+	body := `{"ids":[` + strconv.Itoa(a.ID) + `],"status":"done-ish"}`
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/bulk-status", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	for _, task := range listTestTasks(t, router, "?status=pending") {
+		if task.ID == a.ID {
+			return
+		}
+	}
+	t.Error("task status should be unchanged")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxCommentLength = 10000
+
+// Comment is one entry in a task's discussion thread. Author is the
+// authenticated user who posted it, or null for anonymous requests.
+type Comment struct {
+	ID        int     `json:"id"`
+	TaskID    int     `json:"task_id"`
+	Body      string  `json:"body"`
+	Author    *string `json:"author"`
+	CreatedAt string  `json:"created_at"`
+}
+
+const commentColumns = "id, task_id, body, author, created_at"
+
+func scanComment(row rowScanner) (Comment, error) {
+	var comment Comment
+	err := row.Scan(&comment.ID, &comment.TaskID, &comment.Body, &comment.Author, &comment.CreatedAt)
+	normalizeTimestamp(&comment.CreatedAt, time.UTC)
+	return comment, err
+}
+
+// validateComment trims the body in place and checks it is present and
+// within maxCommentLength runes.
+func validateComment(comment *Comment) error {
+	comment.Body = strings.TrimSpace(comment.Body)
+	if comment.Body == "" {
+		return newValidationError(msgCommentRequired)
+	}
+	if utf8.RuneCountInString(comment.Body) > maxCommentLength {
+		return newValidationError(msgCommentTooLong, maxCommentLength)
+	}
+	return nil
+}
+
+// commentTask resolves the :id of a comments route to a task the caller can
+// see, answering 400 or 404 itself when it can't.
+func commentTask(c *gin.Context) (int, bool) {
+	id, ok := taskIDParam(c)
+	if !ok {
+		return 0, false
+	}
+	if _, err := lookupTask(c, db(), id); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, errCodeNotFound, "Task not found")
+		} else {
+			respondInternalError(c, err)
+		}
+		return 0, false
+	}
+	return id, true
+}
+
+// listComments returns a task's comments, oldest first.
+func listComments(c *gin.Context) {
+	ctx := c.Request.Context()
+	taskID, ok := commentTask(c)
+	if !ok {
+		return
+	}
+
+	rows, err := db().QueryContext(ctx, "SELECT "+commentColumns+" FROM comments WHERE task_id = ? ORDER BY id", taskID)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer rows.Close()
+
+	loc := requestLocation(c)
+	comments := []Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		normalizeTimestamp(&comment.CreatedAt, loc)
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// createComment adds a comment to a task on behalf of the current user.
+func createComment(c *gin.Context) {
+	ctx := c.Request.Context()
+	taskID, ok := commentTask(c)
+	if !ok {
+		return
+	}
+
+	var comment Comment
+	if err := c.ShouldBindJSON(&comment); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := validateComment(&comment); err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+
+	comment.TaskID = taskID
+	comment.Author = nullableString(currentUser(c))
+	err := db().QueryRowContext(ctx, "INSERT INTO comments (task_id, body, author, created_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP) RETURNING id",
+		comment.TaskID, comment.Body, comment.Author).Scan(&comment.ID)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	if err := db().QueryRowContext(ctx, "SELECT created_at FROM comments WHERE id = ?", comment.ID).Scan(&comment.CreatedAt); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	normalizeTimestamp(&comment.CreatedAt, time.UTC)
+
+	c.JSON(http.StatusCreated, comment)
+}
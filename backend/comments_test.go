@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func postTestComment(t *testing.T, router *gin.Engine, taskID int, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/"+strconv.Itoa(taskID)+"/comments", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestComments(t *testing.T) {
+	router := setupTestRouter()
+	task := createTestTask(t, router, Task{Title: "Discuss me"})
+
+	w := postTestComment(t, router, task.ID, `{"body":"  First!  ","author":"spoofed"}`)
+	assert.Equal(t, 201, w.Code)
+	var first Comment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+	assert.Equal(t, task.ID, first.TaskID)
+	assert.Equal(t, "First!", first.Body)
+	assert.Nil(t, first.Author, "author comes from authentication, not the body")
+	assert.NotEmpty(t, first.CreatedAt)
+
+	assert.Equal(t, 201, postTestComment(t, router, task.ID, `{"body":"Second"}`).Code)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/"+strconv.Itoa(task.ID)+"/comments", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var comments []Comment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &comments))
+	if assert.Len(t, comments, 2) {
+		assert.Equal(t, first, comments[0])
+		assert.Equal(t, "Second", comments[1].Body)
+	}
+}
+
+func TestCommentsValidation(t *testing.T) {
+	router := setupTestRouter()
+	task := createTestTask(t, router, Task{Title: "Discuss me"})
+
+	assert.Equal(t, 400, postTestComment(t, router, task.ID, `{"body":"   "}`).Code)
+	assert.Equal(t, 400, postTestComment(t, router, task.ID, `{}`).Code)
+	assert.Equal(t, 404, postTestComment(t, router, 99999, `{"body":"Hello"}`).Code)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/99999/comments", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestCommentsCascadeOnTaskDelete(t *testing.T) {
+	router := setupTestRouter()
+	task := createTestTask(t, router, Task{Title: "Short-lived"})
+	assert.Equal(t, 201, postTestComment(t, router, task.ID, `{"body":"Bye"}`).Code)
+
+	_, err := db().Exec("DELETE FROM tasks WHERE id = ?", task.ID)
+	assert.NoError(t, err)
+
+	var count int
+	assert.NoError(t, db().QueryRow("SELECT COUNT(*) FROM comments WHERE task_id = ?", task.ID).Scan(&count))
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultCompressionMinBytes = 1024
+
+// incompressibleTypes are content type prefixes that are already compressed,
+// where gzip only costs CPU.
+var incompressibleTypes = []string{
+	"image/", "video/", "audio/",
+	"application/gzip", "application/zip", "application/x-gzip",
+	"application/octet-stream", "text/event-stream",
+}
+
+func compressionMinBytes() int {
+	if config().App.Compression.MinBytes > 0 {
+		return config().App.Compression.MinBytes
+	}
+	return defaultCompressionMinBytes
+}
+
+// compressionMiddleware gzips responses of at least app.compression.min_bytes
+// for clients that send Accept-Encoding: gzip. The body is buffered up to the
+// threshold, so small responses go out untouched and with no added latency
+// beyond the buffer.
+func compressionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config().App.Compression.Enabled || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		w := &gzipWriter{ResponseWriter: c.Writer, minBytes: compressionMinBytes()}
+		c.Writer = w
+		defer func() {
+			w.finish()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip, honouring
+// q=0 as a refusal.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		return q > 0
+	}
+	return false
+}
+
+// gzipWriter holds back the first minBytes of a response. Once the body
+// reaches the threshold it switches to gzip; if the handler finishes first,
+// the buffered bytes are written as they are.
+type gzipWriter struct {
+	gin.ResponseWriter
+	minBytes int
+	buf      bytes.Buffer
+	gz       *gzip.Writer
+	// passthrough is set once the response is known not to be compressed.
+	passthrough bool
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	switch {
+	case w.gz != nil:
+		return w.gz.Write(data)
+	case w.passthrough:
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf.Write(data)
+	if w.buf.Len() < w.minBytes {
+		return len(data), nil
+	}
+	if !w.compressible() {
+		return len(data), w.flushBuffer()
+	}
+
+	header := w.Header()
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	if _, err := w.gz.Write(w.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	w.buf.Reset()
+	return len(data), nil
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends whatever is buffered so streaming responses aren't held back.
+// A response that hasn't reached the threshold by its first flush is sent
+// uncompressed.
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	} else {
+		w.flushBuffer()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *gzipWriter) compressible() bool {
+	if w.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusNotModified:
+		return false
+	}
+	contentType := strings.ToLower(w.Header().Get("Content-Type"))
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *gzipWriter) flushBuffer() error {
+	w.passthrough = true
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// finish completes the response once the handler chain has returned.
+func (w *gzipWriter) finish() {
+	if w.gz != nil {
+		w.gz.Close()
+		return
+	}
+	w.flushBuffer()
+}
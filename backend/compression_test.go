@@ -0,0 +1,73 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressionGzipsLargeResponses(t *testing.T) {
+	router := setupTestRouter()
+	config().App.Compression.Enabled = true
+	for i := 0; i < 20; i++ {
+		createTestTask(t, router, Task{Title: "A task with a reasonably long title", Description: strings.Repeat("detail ", 10)})
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+
+	gz, err := gzip.NewReader(w.Body)
+	if !assert.NoError(t, err) {
+		return
+	}
+	body, err := io.ReadAll(gz)
+	assert.NoError(t, err)
+	var tasks []Task
+	assert.NoError(t, json.Unmarshal(body, &tasks))
+	assert.Len(t, tasks, 23)
+}
+
+func TestCompressionSkipsSmallAndUnwantedResponses(t *testing.T) {
+	router := setupTestRouter()
+	config().App.Compression.Enabled = true
+
+	// Below the threshold.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/health", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	router.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Body.String(), "healthy")
+
+	// Client doesn't accept gzip.
+	config().App.Compression.MinBytes = 1
+	for _, encoding := range []string{"", "identity", "gzip;q=0"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/api/v1/tasks", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		router.ServeHTTP(w, req)
+		assert.Empty(t, w.Header().Get("Content-Encoding"), encoding)
+		assert.True(t, json.Valid(w.Body.Bytes()), encoding)
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	assert.True(t, acceptsGzip("gzip"))
+	assert.True(t, acceptsGzip("br, GZIP;q=0.5"))
+	assert.True(t, acceptsGzip("*"))
+	assert.False(t, acceptsGzip(""))
+	assert.False(t, acceptsGzip("br"))
+	assert.False(t, acceptsGzip("gzip;q=0"))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// applyEnvOverrides lets environment variables override any scalar or list
+// setting, so one image runs in every environment. A variable's name is the
+// setting's YAML path upper-cased and joined with underscores, e.g.
+// APP_ENVIRONMENT, DATABASE_PATH or SECURITY_RATE_LIMIT_ENABLED. Lists are
+// comma-separated. Unset or empty variables leave the file's value alone, so
+// the precedence is environment, then config file, then built-in default.
+// PORT is honored as an alias of APP_PORT for platforms that set it.
+func applyEnvOverrides(cfg *Config, lookup func(string) (string, bool)) error {
+	if port, ok := lookup("PORT"); ok && port != "" {
+		if appPort, _ := lookup("APP_PORT"); appPort == "" {
+			lookup = withEnvAlias(lookup, "APP_PORT", port)
+		}
+	}
+	return overrideFromEnv(reflect.ValueOf(cfg).Elem(), "", lookup)
+}
+
+func withEnvAlias(lookup func(string) (string, bool), key, value string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		if name == key {
+			return value, true
+		}
+		return lookup(name)
+	}
+}
+
+func overrideFromEnv(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + strings.ToUpper(name)
+		field := v.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := overrideFromEnv(field, key+"_", lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, ok := lookup(key)
+		if !ok || raw == "" {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(raw)
+		case reflect.Int, reflect.Int64:
+			n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s must be an integer, got %q", key, raw)
+			}
+			field.SetInt(n)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(strings.TrimSpace(raw))
+			if err != nil {
+				return fmt.Errorf("%s must be true or false, got %q", key, raw)
+			}
+			field.SetBool(b)
+		case reflect.Slice:
+			if field.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			var items []string
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			field.Set(reflect.ValueOf(items))
+		}
+	}
+	return nil
+}
+
+// validateConfig checks the settings that would otherwise only fail later,
+// and in confusing ways, once the server is running. Every problem found is
+// reported, not just the first.
+func validateConfig(cfg Config) error {
+	var problems []error
+
+	if cfg.App.Port < 1 || cfg.App.Port > 65535 {
+		problems = append(problems, fmt.Errorf("app.port must be between 1 and 65535, got %d", cfg.App.Port))
+	}
+
+	kind, err := parseDatabaseType(cfg.Database.Type)
+	if err != nil {
+		problems = append(problems, fmt.Errorf("database.type: %w", err))
+	}
+	if kind == dbSQLite && strings.TrimSpace(cfg.Database.Path) == "" {
+		problems = append(problems, errors.New("database.path is required for sqlite"))
+	}
+
+	if _, err := newLogger(io.Discard, cfg.Logging.Level, cfg.Logging.Format); err != nil {
+		problems = append(problems, fmt.Errorf("logging: %w", err))
+	}
+
+	switch cfg.Security.AuthMode {
+	case "", authModeNone, authModeJWT, authModeAPIKey:
+	default:
+		problems = append(problems, fmt.Errorf("security.auth_mode must be one of %s, %s, %s, got %q", authModeNone, authModeJWT, authModeAPIKey, cfg.Security.AuthMode))
+	}
+
+	if err := validateTLS(cfg.Security.TLS); err != nil {
+		problems = append(problems, err)
+	}
+
+	return errors.Join(problems...)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func validTestConfig() Config {
+	var cfg Config
+	cfg.App.Port = 8080
+	cfg.Database.Type = "sqlite"
+	cfg.Database.Path = "./data.db"
+	cfg.Logging.Level = "info"
+	cfg.Logging.Format = "json"
+	return cfg
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		errs   []string
+	}{
+		{name: "valid", modify: func(*Config) {}},
+		{name: "defaults for type and logging", modify: func(c *Config) {
+			c.Database.Type = ""
+			c.Logging.Level = ""
+			c.Logging.Format = ""
+		}},
+		{name: "postgres needs no path", modify: func(c *Config) {
+			c.Database.Type = "postgres"
+			c.Database.Path = ""
+		}},
+		{name: "port zero", modify: func(c *Config) { c.App.Port = 0 }, errs: []string{"app.port must be between 1 and 65535, got 0"}},
+		{name: "port too large", modify: func(c *Config) { c.App.Port = 70000 }, errs: []string{"app.port"}},
+		{name: "unsupported database", modify: func(c *Config) { c.Database.Type = "mysql" }, errs: []string{`unsupported database type "mysql"`}},
+		{name: "missing sqlite path", modify: func(c *Config) { c.Database.Path = " " }, errs: []string{"database.path is required"}},
+		{name: "unknown log level", modify: func(c *Config) { c.Logging.Level = "verbose" }, errs: []string{`unknown log level "verbose"`}},
+		{name: "unknown log format", modify: func(c *Config) { c.Logging.Format = "xml" }, errs: []string{`unknown log format "xml"`}},
+		{name: "unknown auth mode", modify: func(c *Config) { c.Security.AuthMode = "oauth" }, errs: []string{"security.auth_mode"}},
+		{name: "every problem is reported", modify: func(c *Config) {
+			c.App.Port = -1
+			c.Database.Path = ""
+			c.Logging.Level = "loud"
+		}, errs: []string{"app.port", "database.path", "log level"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validTestConfig()
+			tt.modify(&cfg)
+
+			err := validateConfig(cfg)
+			if len(tt.errs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				for _, want := range tt.errs {
+					assert.Contains(t, err.Error(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestShippedConfigIsValid(t *testing.T) {
+	data, err := os.ReadFile("config.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var cfg Config
+	assert.NoError(t, yaml.Unmarshal(data, &cfg))
+	assert.NoError(t, validateConfig(cfg))
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	env := map[string]string{
+		"APP_NAME":                "from-env",
+		"APP_PORT":                "9090",
+		"PORT":                    "7070",
+		"APP_MAX_BODY_BYTES":      "2048",
+		"APP_COMPRESSION_ENABLED": "true",
+		"DATABASE_PATH":           "/data/tasks.db",
+		"LOGGING_LEVEL":           "debug",
+		"LOGGING_FORMAT":          "",
+		"SECURITY_CORS_ORIGINS":   "https://a.example, https://b.example,",
+		"SECURITY_RATE_LIMIT_REQUESTS_PER_MINUTE": "30",
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	cfg := validTestConfig()
+	cfg.App.Name = "from-file"
+	assert.NoError(t, applyEnvOverrides(&cfg, lookup))
+
+	assert.Equal(t, "from-env", cfg.App.Name)
+	assert.Equal(t, 9090, cfg.App.Port, "APP_PORT wins over the PORT alias")
+	assert.Equal(t, int64(2048), cfg.App.MaxBodyBytes)
+	assert.True(t, cfg.App.Compression.Enabled)
+	assert.Equal(t, "/data/tasks.db", cfg.Database.Path)
+	assert.Equal(t, "debug", cfg.Logging.Level)
+	assert.Equal(t, "json", cfg.Logging.Format, "empty variables leave the file's value")
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.Security.CorsOrigins)
+	assert.Equal(t, 30, cfg.Security.RateLimit.RequestsPerMinute)
+
+	delete(env, "APP_PORT")
+	assert.NoError(t, applyEnvOverrides(&cfg, lookup))
+	assert.Equal(t, 7070, cfg.App.Port)
+}
+
+func TestApplyEnvOverridesInvalid(t *testing.T) {
+	for key, value := range map[string]string{"APP_PORT": "eighty", "DATABASE_SEED": "sometimes"} {
+		cfg := validTestConfig()
+		err := applyEnvOverrides(&cfg, func(k string) (string, bool) {
+			if k == key {
+				return value, true
+			}
+			return "", false
+		})
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), key)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+const (
+	maxConnInitStatements     = 10
+	maxConnInitStatementBytes = 512
+)
+
+// connInitPrefixes are the only statement kinds allowed to run when a
+// connection opens: session settings, never data changes.
+var connInitPrefixes = []string{"SET ", "PRAGMA "}
+
+// validateConnInitStatements rejects anything other than a short list of
+// single session-setting statements that kind of database understands:
+// PostgreSQL has no PRAGMA and would fail every connection on one.
+func validateConnInitStatements(kind string, statements []string) error {
+	if len(statements) > maxConnInitStatements {
+		return fmt.Errorf("at most %d connection init statements are allowed", maxConnInitStatements)
+	}
+	for _, stmt := range statements {
+		stmt = strings.TrimSpace(stmt)
+		if len(stmt) > maxConnInitStatementBytes {
+			return fmt.Errorf("connection init statement exceeds %d bytes", maxConnInitStatementBytes)
+		}
+		if strings.Contains(strings.TrimSuffix(stmt, ";"), ";") {
+			return fmt.Errorf("connection init statement %q must be a single statement", stmt)
+		}
+
+		allowed := false
+		upper := strings.ToUpper(stmt)
+		for _, prefix := range connInitPrefixes {
+			if strings.HasPrefix(upper, prefix) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("connection init statement %q must start with SET or PRAGMA", stmt)
+		}
+		if kind == dbPostgres && strings.HasPrefix(upper, "PRAGMA ") {
+			return fmt.Errorf("connection init statement %q is a PRAGMA, which PostgreSQL doesn't support", stmt)
+		}
+	}
+	return nil
+}
+
+// initConnector opens connections through the wrapped driver and runs the
+// configured session statements on each one before handing it to the pool.
+// With rebind set, connections rewrite ? placeholders for PostgreSQL.
+type initConnector struct {
+	driver     driver.Driver
+	dsn        string
+	statements []string
+	rebind     bool
+}
+
+func newInitConnector(d driver.Driver, dsn string, statements []string) *initConnector {
+	return &initConnector{driver: d, dsn: dsn, statements: statements}
+}
+
+func (c *initConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range c.statements {
+		if err := execOnConn(ctx, conn, stmt); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("connection init statement %q: %w", stmt, err)
+		}
+	}
+	if c.rebind {
+		return rebindConn{conn}, nil
+	}
+	return conn, nil
+}
+
+func (c *initConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func execOnConn(ctx context.Context, conn driver.Conn, query string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, nil)
+		return err
+	}
+
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(nil)
+	return err
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnInitStatementsApplied(t *testing.T) {
+	setupTestRouter()
+	config().Database.ConnInitStatements = []string{"PRAGMA foreign_keys = ON"}
+	assert.NoError(t, initDatabase())
+
+	var enabled int
+	err := db().QueryRow("PRAGMA foreign_keys").Scan(&enabled)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, enabled)
+}
+
+func TestConnInitStatementFailureSurfaces(t *testing.T) {
+	setupTestRouter()
+
+	// SET is allowed by validation but isn't valid SQLite, so opening fails
+	config().Database.ConnInitStatements = []string{"SET search_path TO app"}
+	config().Database.ConnectAttempts = 1
+	err := initDatabase()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "connection init statement")
+}
+
+func TestValidateConnInitStatements(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       string
+		statements []string
+		valid      bool
+	}{
+		{"empty", dbSQLite, nil, true},
+		{"pragma", dbSQLite, []string{"PRAGMA busy_timeout = 5000"}, true},
+		{"set", dbPostgres, []string{"set search_path TO app, public;"}, true},
+		{"pragma on postgres", dbPostgres, []string{"PRAGMA foreign_keys = ON"}, false},
+		{"data change", dbSQLite, []string{"DROP TABLE tasks"}, false},
+		{"stacked", dbPostgres, []string{"SET a = 1; DELETE FROM tasks"}, false},
+		{"too long", dbPostgres, []string{"SET x = '" + strings.Repeat("a", maxConnInitStatementBytes) + "'"}, false},
+		{"too many", dbSQLite, make([]string, maxConnInitStatements+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConnInitStatements(tt.kind, tt.statements)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
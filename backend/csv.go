@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// csvHeader is the column layout of CSV exports.
+var csvHeader = []string{"id", "title", "description", "status", "priority", "due_date", "recurrence", "parent_id", "created_at", "updated_at", "completed_at", "created_by", "updated_by", "assignee"}
+
+// csvFlushEvery bounds how many rows are buffered before being sent.
+const csvFlushEvery = 100
+
+// csvFormulaPrefixes start cells that spreadsheets evaluate as formulas.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvCell neutralizes text a spreadsheet would run as a formula by prefixing
+// it with an apostrophe, so a task titled "=HYPERLINK(...)" stays text.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvValue undoes csvCell, so exported files import unchanged.
+func csvValue(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+func csvOptional(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return csvCell(*s)
+}
+
+func taskCSVRecord(task Task) []string {
+	parentID := ""
+	if task.ParentID != nil {
+		parentID = strconv.Itoa(*task.ParentID)
+	}
+	completedAt := ""
+	if task.CompletedAt != nil {
+		completedAt = task.CompletedAt.String()
+	}
+	return []string{
+		strconv.Itoa(task.ID),
+		csvCell(task.Title),
+		csvCell(task.Description),
+		task.Status,
+		task.Priority,
+		csvOptional(task.DueDate),
+		task.Recurrence,
+		parentID,
+		task.CreatedAt.String(),
+		task.UpdatedAt.String(),
+		completedAt,
+		csvOptional(task.CreatedBy),
+		csvOptional(task.UpdatedBy),
+		csvOptional(task.Assignee),
+	}
+}
+
+// exportTasksCSV streams the tasks matching the getTasks filters as a CSV
+// attachment. Rows are written as they are read, so large exports aren't
+// held in memory. Once streaming has started the status can't change, so a
+// failure part way through is logged and the download is cut short.
+func exportTasksCSV(c *gin.Context) {
+	ctx := c.Request.Context()
+	where, args, err := taskListWhere(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+
+	position, _ := taskPositionColumn(c)
+	rows, err := db().QueryContext(ctx, "SELECT "+taskColumns+" FROM tasks"+where+" ORDER BY "+taskOrderClause(c.Query("sort"), position), args...)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="tasks.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(csvHeader)
+	for n := 1; rows.Next(); n++ {
+		task, err := scanTask(rows)
+		if err != nil {
+			requestLogger(c).Error("csv export aborted", "error", err)
+			break
+		}
+		w.Write(taskCSVRecord(task))
+		if n%csvFlushEvery == 0 {
+			w.Flush()
+			c.Writer.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		requestLogger(c).Error("csv export aborted", "error", err)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		requestLogger(c).Warn("csv export interrupted", "error", err)
+	}
+}
+
+// maxCSVImportRows caps how many data rows a single CSV upload may contain.
+const maxCSVImportRows = 1000
+
+// CSVImportError reports why one row of a CSV upload was skipped. Row is the
+// line number in the file, counting the header as row 1.
+type CSVImportError struct {
+	Row    int    `json:"row"`
+	Reason string `json:"reason"`
+}
+
+// CSVImportResult summarizes a CSV upload.
+type CSVImportResult struct {
+	Imported int              `json:"imported"`
+	Skipped  int              `json:"skipped"`
+	Errors   []CSVImportError `json:"errors"`
+}
+
+// csvRow is a data row of an upload along with the line it started on.
+type csvRow struct {
+	line   int
+	fields []string
+}
+
+// readCSVImport reads the header and data rows of an upload, returning the
+// header's column positions by lower-cased name.
+func readCSVImport(r io.Reader) (map[string]int, []csvRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, dup := columns[name]; !dup {
+			columns[name] = i
+		}
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, nil, errors.New("CSV must have a title column")
+	}
+
+	var rows []csvRow
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return columns, rows, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if len(rows) == maxCSVImportRows {
+			return nil, nil, fmt.Errorf("CSV must not exceed %d rows", maxCSVImportRows)
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, csvRow{line: line, fields: fields})
+	}
+}
+
+// csvTask builds a task from the known columns of a row. Columns the export
+// writes but that the database assigns, like id and created_at, are ignored.
+func csvTask(columns map[string]int, fields []string) Task {
+	get := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(fields) {
+			return ""
+		}
+		return csvValue(strings.TrimSpace(fields[i]))
+	}
+
+	task := Task{
+		Title:       get("title"),
+		Description: get("description"),
+		Status:      get("status"),
+		Priority:    get("priority"),
+		Recurrence:  get("recurrence"),
+	}
+	if due := get("due_date"); due != "" {
+		task.DueDate = &due
+	}
+	if assignee := get("assignee"); assignee != "" {
+		task.Assignee = &assignee
+	}
+	return task
+}
+
+// importTasksCSV creates a task from every valid row of an uploaded CSV file,
+// in the "file" form field, within one transaction. Rows that fail validation
+// are skipped and reported instead of aborting the import.
+func importTasksCSV(c *gin.Context) {
+	ctx := c.Request.Context()
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondBindError(c, err)
+			return
+		}
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "a CSV file is required in the file field")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer file.Close()
+
+	columns, rows, err := readCSVImport(file)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer tx.Rollback()
+
+	result := CSVImportResult{Errors: []CSVImportError{}}
+	var created []Task
+	user := currentUser(c)
+	for _, row := range rows {
+		task := csvTask(columns, row.fields)
+		if err := validateTask(&task); err != nil {
+			result.Skipped++
+			result.Errors = append(result.Errors, CSVImportError{Row: row.line, Reason: localize(c, err)})
+			continue
+		}
+		if task.Status == "" {
+			task.Status = "pending"
+		}
+		if config().App.TitleAutoSuffix {
+			title, err := nextAvailableTitle(ctx, tx, task.Title)
+			if err != nil {
+				respondInternalError(c, err)
+				return
+			}
+			task.Title = title
+		}
+
+		if err := insertTask(ctx, tx, &task, user); err != nil {
+			respondInternalError(c, fmt.Errorf("row %d: %w", row.line, err))
+			return
+		}
+		created = append(created, task)
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	for _, task := range created {
+		publishTaskEvent(eventTaskCreated, task)
+	}
+
+	result.Imported = len(created)
+	c.JSON(http.StatusOK, result)
+}
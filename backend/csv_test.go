@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportTasksCSV(t *testing.T) {
+	router := setupTestRouter()
+	createTestTask(t, router, Task{Title: `Quote "this", please`, Description: "line one\nline two"})
+	createTestTask(t, router, Task{Title: "=HYPERLINK(\"http://evil\")"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/export.csv?status=pending&sort=id", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.NoError(t, err)
+	if !assert.NotEmpty(t, records) {
+		return
+	}
+	assert.Equal(t, csvHeader, records[0])
+
+	byTitle := map[string][]string{}
+	for _, record := range records[1:] {
+		assert.Equal(t, "pending", record[3], "status filter applies")
+		byTitle[record[1]] = record
+	}
+	if record, ok := byTitle[`Quote "this", please`]; assert.True(t, ok) {
+		assert.Equal(t, "line one\nline two", record[2])
+	}
+	assert.Contains(t, byTitle, "'=HYPERLINK(\"http://evil\")", "formulas are neutralized")
+}
+
+func TestExportTasksCSVInvalidFilter(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/export.csv?created_after=yesterday", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+}
+
+func postTestCSV(t *testing.T, router *gin.Engine, content string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "tasks.csv")
+	assert.NoError(t, err)
+	part.Write([]byte(content))
+	form.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestImportTasksCSV(t *testing.T) {
+	router := setupTestRouter()
+
+	w := postTestCSV(t, router, "Title,Priority,Status,Due_Date\n"+
+		"Write docs,high,in_progress,2030-01-02T15:04:05Z\n"+
+		",low,,\n"+
+		"\"Comma, and \"\"quotes\"\"\",,,\n"+
+		"Bad priority,urgent,,\n"+
+		"'=1+1,,,\n")
+	assert.Equal(t, 200, w.Code)
+
+	var result CSVImportResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 3, result.Imported)
+	assert.Equal(t, 2, result.Skipped)
+	if assert.Len(t, result.Errors, 2) {
+		assert.Equal(t, 3, result.Errors[0].Row)
+		assert.Equal(t, 5, result.Errors[1].Row)
+		assert.NotEmpty(t, result.Errors[0].Reason)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks?q=quotes", nil)
+	router.ServeHTTP(w, req)
+	var tasks []Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, `Comma, and "quotes"`, tasks[0].Title)
+		assert.Equal(t, "pending", tasks[0].Status)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks?q=%3D1", nil)
+	router.ServeHTTP(w, req)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, "=1+1", tasks[0].Title, "the export's formula guard is undone")
+	}
+}
+
+func TestImportTasksCSVRejected(t *testing.T) {
+	router := setupTestRouter()
+
+	assert.Equal(t, 400, postTestCSV(t, router, "").Code)
+	assert.Equal(t, 400, postTestCSV(t, router, "description\nno title column\n").Code)
+	assert.Equal(t, 400, postTestCSV(t, router, "title\n\"unterminated\n").Code)
+	assert.Equal(t, 400, postTestCSV(t, router, "title\n"+strings.Repeat("Task\n", maxCSVImportRows+1)).Code)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/import", strings.NewReader("--x--\r\n"))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code, "file field is required")
+}
+
+func TestExportImportCSVRoundTrip(t *testing.T) {
+	router := setupTestRouter()
+	createTestTask(t, router, Task{Title: "@mention me", Description: "a, b\n\"c\"", Priority: "low"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/export.csv?q=mention", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	w = postTestCSV(t, router, w.Body.String())
+	var result CSVImportResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 1, result.Imported)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks?q=mention&sort=id", nil)
+	router.ServeHTTP(w, req)
+	var tasks []Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, tasks[0].Title, tasks[1].Title)
+		assert.Equal(t, tasks[0].Description, tasks[1].Description)
+		assert.Equal(t, tasks[0].Priority, tasks[1].Priority)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	dbSQLite   = "sqlite"
+	dbPostgres = "postgres"
+
+	defaultPostgresPort = 5432
+
+	defaultMaxConnections         = 25
+	defaultMaxIdleConnections     = 5
+	defaultConnMaxLifetime        = 30 * time.Minute
+	defaultDatabaseTimeoutSeconds = 5
+	defaultConnectAttempts        = 5
+	defaultConnectBackoff         = 500 * time.Millisecond
+)
+
+// databaseType normalizes config.Database.Type; SQLite is the default.
+func databaseType() (string, error) {
+	return parseDatabaseType(config().Database.Type)
+}
+
+func parseDatabaseType(kind string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "", "sqlite", "sqlite3":
+		return dbSQLite, nil
+	case "postgres", "postgresql":
+		return dbPostgres, nil
+	default:
+		return "", fmt.Errorf("unsupported database type %q (use sqlite or postgres)", kind)
+	}
+}
+
+// isPostgres reports whether the configured database is PostgreSQL, for the
+// few queries that have to differ between dialects.
+func isPostgres() bool {
+	kind, _ := databaseType()
+	return kind == dbPostgres
+}
+
+// postgresDSN builds a connection URL from the DB_HOST, DB_USER and
+// DB_PASSWORD environment variables and the database section of the config.
+func postgresDSN() string {
+	host := os.Getenv("DB_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	port := config().Database.Port
+	if port == 0 {
+		port = defaultPostgresPort
+	}
+
+	dsn := url.URL{
+		Scheme: "postgres",
+		Host:   host + ":" + strconv.Itoa(port),
+		Path:   "/" + config().Database.Name,
+	}
+	if user := os.Getenv("DB_USER"); user != "" {
+		if password := os.Getenv("DB_PASSWORD"); password != "" {
+			dsn.User = url.UserPassword(user, password)
+		} else {
+			dsn.User = url.User(user)
+		}
+	}
+	if config().Database.SSLMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": {config().Database.SSLMode}}.Encode()
+	}
+	return dsn.String()
+}
+
+// openDatabase opens the configured database. Queries throughout the code use
+// SQLite-style ? placeholders; on PostgreSQL they are rewritten to $n as each
+// statement is prepared.
+func openDatabase() (*sql.DB, error) {
+	kind, err := databaseType()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateConnInitStatements(kind, config().Database.ConnInitStatements); err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case dbPostgres:
+		connector := newInitConnector(&pq.Driver{}, postgresDSN(), config().Database.ConnInitStatements)
+		connector.rebind = true
+		return sql.OpenDB(connector), nil
+	default:
+		// SQLite leaves foreign keys off unless asked per connection, and the
+		// schema relies on them to cascade deletes.
+		statements := append([]string{"PRAGMA foreign_keys = ON"}, config().Database.ConnInitStatements...)
+		return sql.OpenDB(newInitConnector(&sqlite3.SQLiteDriver{}, config().Database.Path, statements)), nil
+	}
+}
+
+// configurePool applies database.max_connections to the pool. An in-memory
+// SQLite database exists per connection, so it is pinned to a single
+// connection that never expires or every new connection would see an empty
+// database.
+func configurePool(conn *sql.DB) {
+	if !isPostgres() && isMemoryDSN(config().Database.Path) {
+		conn.SetMaxOpenConns(1)
+		conn.SetMaxIdleConns(1)
+		conn.SetConnMaxLifetime(0)
+		return
+	}
+
+	maxOpen := config().Database.MaxConnections
+	if maxOpen <= 0 {
+		maxOpen = defaultMaxConnections
+	}
+	maxIdle := defaultMaxIdleConnections
+	if maxIdle > maxOpen {
+		maxIdle = maxOpen
+	}
+
+	conn.SetMaxOpenConns(maxOpen)
+	conn.SetMaxIdleConns(maxIdle)
+	conn.SetConnMaxLifetime(defaultConnMaxLifetime)
+}
+
+func isMemoryDSN(dsn string) bool {
+	return dsn == ":memory:" || strings.Contains(dsn, "mode=memory")
+}
+
+// databaseTimeout bounds how long startup waits for the database to answer.
+func databaseTimeout() time.Duration {
+	if config().Database.Timeout > 0 {
+		return time.Duration(config().Database.Timeout) * time.Second
+	}
+	return defaultDatabaseTimeoutSeconds * time.Second
+}
+
+func connectAttempts() int {
+	if config().Database.ConnectAttempts > 0 {
+		return config().Database.ConnectAttempts
+	}
+	return defaultConnectAttempts
+}
+
+func connectBackoff() time.Duration {
+	if config().Database.ConnectBackoffMillis > 0 {
+		return time.Duration(config().Database.ConnectBackoffMillis) * time.Millisecond
+	}
+	return defaultConnectBackoff
+}
+
+// connectDatabase opens the database and pings it, retrying with exponential
+// backoff so the app can start before the database is ready, as happens when
+// containers start in no particular order.
+func connectDatabase() (*sql.DB, error) {
+	conn, err := openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(conn)
+
+	attempts, backoff := connectAttempts(), connectBackoff()
+	for attempt := 1; ; attempt++ {
+		err = pingDatabase(conn)
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("database is reachable", "attempt", attempt)
+			}
+			return conn, nil
+		}
+		if attempt >= attempts {
+			conn.Close()
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+		}
+		logger.Warn("database not ready, retrying", "attempt", attempt, "max_attempts", attempts, "retry_in", backoff.String(), "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// pingDatabase makes sure the database is reachable so a misconfiguration
+// fails at startup instead of on the first request.
+func pingDatabase(conn *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), databaseTimeout())
+	defer cancel()
+	if err := conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("database is unreachable: %w", err)
+	}
+	return nil
+}
+
+// lockMatchKey serializes transactions that look for a row and insert it if
+// missing. PostgreSQL takes a transaction-scoped advisory lock on key, so
+// only callers with the same key wait for each other. SQLite has a single
+// writer, so an empty UPDATE takes the database write lock up front, as
+// BEGIN IMMEDIATE would, before anything is read.
+func lockMatchKey(ctx context.Context, tx *sql.Tx, key string) error {
+	if isPostgres() {
+		_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext(?))", key)
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "UPDATE tasks SET id = id WHERE 1 = 0")
+	return err
+}
+
+// isLockConflict reports whether err means a concurrent transaction held a
+// lock this one needed: SQLite's busy or locked database, or a PostgreSQL
+// serialization failure, deadlock or lock timeout. Retrying may succeed.
+func isLockConflict(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40001", "40P01", "55P03":
+			return true
+		}
+	}
+	return false
+}
+
+// rebindPlaceholders rewrites ? placeholders to PostgreSQL's $1, $2, ...,
+// leaving question marks inside quoted strings alone.
+func rebindPlaceholders(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	inString := false
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'':
+			inString = !inString
+		case ch == '?' && !inString:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
+
+// rebindConn rewrites placeholders on every statement prepared on the wrapped
+// connection. It deliberately doesn't implement the Queryer/Execer fast paths
+// so that database/sql routes every query through Prepare.
+type rebindConn struct {
+	driver.Conn
+}
+
+func (c rebindConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(rebindPlaceholders(query))
+}
+
+func (c rebindConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, rebindPlaceholders(query))
+	}
+	return c.Prepare(query)
+}
+
+func (c rebindConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DependencyRequest adds a "blocked by" edge from the task in the path to
+// DependsOn.
+type DependencyRequest struct {
+	DependsOn *int `json:"depends_on"`
+}
+
+// DependencyResponse lists what a task is blocked by after a change.
+type DependencyResponse struct {
+	TaskID    int   `json:"task_id"`
+	BlockedBy []int `json:"blocked_by"`
+}
+
+// blockedBy returns the ids of the live tasks that id depends on.
+func blockedBy(ctx context.Context, q dbtx, id int) ([]int, error) {
+	rows, err := q.QueryContext(ctx, "SELECT d.depends_on_id FROM task_dependencies d JOIN tasks b ON b.id = d.depends_on_id WHERE d.task_id = ? AND b.deleted_at IS NULL ORDER BY d.depends_on_id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var dep int
+		if err := rows.Scan(&dep); err != nil {
+			return nil, err
+		}
+		ids = append(ids, dep)
+	}
+	return ids, rows.Err()
+}
+
+// dependsOn reports whether from already depends on to, directly or through
+// other tasks. The walk follows depends_on edges breadth-first in SQL; UNION
+// drops rows already seen, so it terminates on any graph.
+func dependsOn(ctx context.Context, q dbtx, from, to int) (bool, error) {
+	var found int
+	err := q.QueryRowContext(ctx, `WITH RECURSIVE reachable (id) AS (
+		SELECT ?
+		UNION
+		SELECT d.depends_on_id FROM task_dependencies d JOIN reachable r ON d.task_id = r.id
+	) SELECT COUNT(*) FROM reachable WHERE id = ?`, from, to).Scan(&found)
+	return found > 0, err
+}
+
+// addDependency records that the task is blocked by another. An edge that
+// would close a cycle is refused with 409, since neither task could ever be
+// started; adding an existing edge is a no-op.
+func addDependency(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := taskIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req DependencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if req.DependsOn == nil {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "depends_on is required")
+		return
+	}
+	dep := *req.DependsOn
+
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := lookupTask(c, tx, id); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, errCodeNotFound, "Task not found")
+		} else {
+			respondInternalError(c, err)
+		}
+		return
+	}
+	if _, err := lookupTask(c, tx, dep); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, newValidationError(msgDependencyNotFound)))
+		} else {
+			respondInternalError(c, err)
+		}
+		return
+	}
+
+	cycle, err := dependsOn(ctx, tx, dep, id)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	if cycle {
+		respondErrorWith(c, http.StatusConflict, errCodeConflict, "dependency would create a cycle", gin.H{"task_id": id, "depends_on": dep})
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO task_dependencies (task_id, depends_on_id) VALUES (?, ?) ON CONFLICT DO NOTHING", id, dep); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	ids, err := blockedBy(ctx, tx, id)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, DependencyResponse{TaskID: id, BlockedBy: ids})
+}
+
+// removeDependency deletes the edge from the task to :depends_on.
+func removeDependency(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := taskIDParam(c)
+	if !ok {
+		return
+	}
+	dep, err := strconv.Atoi(c.Param("depends_on"))
+	if err != nil || dep <= 0 {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, newValidationError(msgInvalidID)))
+		return
+	}
+
+	if _, err := lookupTask(c, db(), id); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, errCodeNotFound, "Task not found")
+		} else {
+			respondInternalError(c, err)
+		}
+		return
+	}
+
+	result, err := db().ExecContext(ctx, "DELETE FROM task_dependencies WHERE task_id = ? AND depends_on_id = ?", id, dep)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(c, http.StatusNotFound, errCodeNotFound, "Dependency not found")
+		return
+	}
+
+	ids, err := blockedBy(ctx, db(), id)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, DependencyResponse{TaskID: id, BlockedBy: ids})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func addTestDependency(t *testing.T, router *gin.Engine, taskID, dependsOn int) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/"+strconv.Itoa(taskID)+"/dependencies", bytes.NewBufferString(fmt.Sprintf(`{"depends_on":%d}`, dependsOn)))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDependencies(t *testing.T) {
+	router := setupTestRouter()
+	design := createTestTask(t, router, Task{Title: "Design"})
+	build := createTestTask(t, router, Task{Title: "Build"})
+	ship := createTestTask(t, router, Task{Title: "Ship"})
+
+	w := addTestDependency(t, router, ship.ID, build.ID)
+	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, 201, addTestDependency(t, router, ship.ID, design.ID).Code)
+	assert.Equal(t, 201, addTestDependency(t, router, ship.ID, design.ID).Code, "adding an existing edge is a no-op")
+
+	var response DependencyResponse
+	w = addTestDependency(t, router, build.ID, design.ID)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, DependencyResponse{TaskID: build.ID, BlockedBy: []int{design.ID}}, response)
+
+	assert.Equal(t, []int{design.ID, build.ID}, getTestTask(t, router, ship.ID).BlockedBy)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/api/v1/tasks/%d/dependencies/%d", ship.ID, build.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, []int{design.ID}, getTestTask(t, router, ship.ID).BlockedBy)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/api/v1/tasks/%d/dependencies/%d", ship.ID, build.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+
+	assert.Equal(t, 404, addTestDependency(t, router, 99999, design.ID).Code)
+	assert.Equal(t, 400, addTestDependency(t, router, design.ID, 99999).Code)
+}
+
+func TestDependencyCyclesAreRejected(t *testing.T) {
+	router := setupTestRouter()
+	a := createTestTask(t, router, Task{Title: "A"})
+	b := createTestTask(t, router, Task{Title: "B"})
+	c := createTestTask(t, router, Task{Title: "C"})
+
+	// a <- b <- c: a is blocked by b, which is blocked by c.
+	assert.Equal(t, 201, addTestDependency(t, router, a.ID, b.ID).Code)
+	assert.Equal(t, 201, addTestDependency(t, router, b.ID, c.ID).Code)
+
+	assert.Equal(t, 409, addTestDependency(t, router, a.ID, a.ID).Code, "self")
+	assert.Equal(t, 409, addTestDependency(t, router, b.ID, a.ID).Code, "direct")
+	assert.Equal(t, 409, addTestDependency(t, router, c.ID, a.ID).Code, "transitive")
+
+	// A diamond is not a cycle.
+	assert.Equal(t, 201, addTestDependency(t, router, a.ID, c.ID).Code)
+}
+
+func TestNextTaskSkipsBlockedTasks(t *testing.T) {
+	router := setupTestRouter()
+	_, err := db().Exec("DELETE FROM tasks")
+	assert.NoError(t, err)
+
+	urgent := createTestTask(t, router, Task{Title: "Urgent but blocked", Priority: "high"})
+	prereq := createTestTask(t, router, Task{Title: "Prerequisite", Priority: "low"})
+	assert.Equal(t, 201, addTestDependency(t, router, urgent.ID, prereq.ID).Code)
+
+	next := func() string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/tasks/next", nil)
+		router.ServeHTTP(w, req)
+		var task Task
+		json.Unmarshal(w.Body.Bytes(), &task)
+		return task.Title
+	}
+	assert.Equal(t, "Prerequisite", next())
+
+	_, err = db().Exec("UPDATE tasks SET status = 'completed' WHERE id = ?", prereq.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Urgent but blocked", next())
+}
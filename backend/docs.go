@@ -0,0 +1,98 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+)
+
+// openAPISpec is the hand-written description of every route. TestOpenAPI
+// fails when a route is registered without being documented, or vice versa.
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+var (
+	openAPIOnce sync.Once
+	openAPIJSON []byte
+	openAPIErr  error
+)
+
+// jsonCompatible converts the map[interface{}]interface{} values yaml.v2
+// decodes into map[string]interface{} so encoding/json can marshal them.
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(value)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = jsonCompatible(v[i])
+		}
+	}
+	return v
+}
+
+// loadOpenAPISpec parses the embedded YAML into a JSON-compatible document.
+func loadOpenAPISpec() (map[string]interface{}, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(openAPISpec, &doc); err != nil {
+		return nil, err
+	}
+	spec, ok := jsonCompatible(doc).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("openapi.yaml is not a mapping")
+	}
+	return spec, nil
+}
+
+// getOpenAPISpec serves the spec as JSON, converted once and stamped with the
+// running version.
+func getOpenAPISpec(c *gin.Context) {
+	openAPIOnce.Do(func() {
+		spec, err := loadOpenAPISpec()
+		if err != nil {
+			openAPIErr = err
+			return
+		}
+		if info, ok := spec["info"].(map[string]interface{}); ok && config().App.Version != "" {
+			info["version"] = config().App.Version
+		}
+		openAPIJSON, openAPIErr = json.Marshal(spec)
+	})
+	if openAPIErr != nil {
+		respondInternalError(c, openAPIErr)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPIJSON)
+}
+
+// swaggerUIPage loads Swagger UI from a CDN and points it at the spec.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>TaskHub API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+func getAPIDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
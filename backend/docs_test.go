@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ginPathParam = regexp.MustCompile(`:(\w+)`)
+
+// collectRefs appends every $ref value found anywhere under v.
+func collectRefs(v interface{}, refs *[]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				*refs = append(*refs, ref)
+			}
+			collectRefs(value, refs)
+		}
+	case []interface{}:
+		for _, value := range v {
+			collectRefs(value, refs)
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	router := setupTestRouter()
+	spec, err := loadOpenAPISpec()
+	if !assert.NoError(t, err) {
+		return
+	}
+	paths := spec["paths"].(map[string]interface{})
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		path, ok := strings.CutPrefix(route.Path, "/api/v1")
+		if !ok {
+			continue
+		}
+		path = ginPathParam.ReplaceAllString(path, "{$1}")
+		method := strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+
+		item, _ := paths[path].(map[string]interface{})
+		assert.Contains(t, item, method, "%s %s is not documented in openapi.yaml", route.Method, path)
+	}
+
+	for path, item := range paths {
+		for method := range item.(map[string]interface{}) {
+			if method == "parameters" {
+				continue
+			}
+			assert.True(t, registered[method+" "+path], "openapi.yaml documents %s %s, which is not a route", method, path)
+		}
+	}
+
+	var refs []string
+	collectRefs(spec, &refs)
+	for _, ref := range refs {
+		parts := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+		var node interface{} = spec
+		for _, part := range parts {
+			m, _ := node.(map[string]interface{})
+			node = m[part]
+		}
+		assert.NotNil(t, node, "unresolved $ref %s", ref)
+	}
+}
+
+func TestOpenAPIEndpoints(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/openapi.json", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec["openapi"])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/docs", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), "openapi.json")
+}
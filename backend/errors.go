@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Error codes are stable, machine-readable identifiers for APIError.Code;
+// clients should branch on these rather than on the message text.
+const (
+	errCodeInvalidRequest       = "invalid_request"
+	errCodeValidation           = "validation_failed"
+	errCodeUnauthorized         = "unauthorized"
+	errCodeForbidden            = "forbidden"
+	errCodeNotFound             = "not_found"
+	errCodeConflict             = "conflict"
+	errCodePreconditionRequired = "precondition_required"
+	errCodePayloadTooLarge      = "payload_too_large"
+	errCodeRateLimited          = "rate_limited"
+	errCodeNotImplemented       = "not_implemented"
+	errCodeTimeout              = "timeout"
+	errCodeInternal             = "internal_error"
+)
+
+// APIError is the body of every error response.
+type APIError struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// respondError aborts the request with an APIError body. When
+// app.error_request_id is enabled the request id is included so users can
+// quote it when reporting a problem.
+func respondError(c *gin.Context, status int, code, message string) {
+	respondErrorWith(c, status, code, message, nil)
+}
+
+// respondErrorWith is respondError with machine-readable details attached.
+func respondErrorWith(c *gin.Context, status int, code, message string, details interface{}) {
+	body := APIError{Code: code, Message: message, Details: details}
+	if config().App.ErrorRequestID {
+		body.RequestID = requestID(c)
+	}
+	if status >= http.StatusInternalServerError {
+		requestLogger(c).Error("request failed", "status", status, "code", code, "error", message)
+	}
+	c.AbortWithStatusJSON(status, body)
+}
+
+// requestError is a failure found by a write path shared between the REST
+// handlers and the GraphQL resolvers, carrying what each needs to report it.
+type requestError struct {
+	status  int
+	code    string
+	message string
+	details interface{}
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+var errTaskNotFound = &requestError{status: http.StatusNotFound, code: errCodeNotFound, message: "Task not found"}
+
+// respondWriteError answers a failed shared write path: request errors with
+// their own status, validation errors with 400 and anything else as internal.
+func respondWriteError(c *gin.Context, err error) {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		respondErrorWith(c, reqErr.status, reqErr.code, reqErr.message, reqErr.details)
+	case isValidationError(err):
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+	default:
+		respondInternalError(c, err)
+	}
+}
+
+// respondInternalError logs err in full with the request id and answers with
+// a generic 500, so database and driver details never reach the client. A
+// request that ran past its deadline gets 503 instead.
+func respondInternalError(c *gin.Context, err error) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		respondError(c, http.StatusServiceUnavailable, errCodeTimeout, "request timed out")
+		return
+	}
+	requestLogger(c).Error("internal error", "method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
+	body := APIError{Code: errCodeInternal, Message: "internal server error"}
+	if config().App.ErrorRequestID {
+		body.RequestID = requestID(c)
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, body)
+}
+
+// respondBindError answers a request whose JSON body couldn't be bound.
+// Decoder errors are reworded so parser internals don't leak to clients; the
+// offending field is named when the decoder knows it. Failed binding tags
+// get a localized message per field, and anything else a generic one.
+func respondBindError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondError(c, http.StatusRequestEntityTooLarge, errCodePayloadTooLarge, fmt.Sprintf("request body must not exceed %d bytes", tooLarge.Limit))
+		return
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var fieldErrs validator.ValidationErrors
+	switch {
+	case errors.Is(err, io.EOF):
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid JSON body: unexpected end of input")
+	case errors.As(err, &syntaxErr):
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid JSON body", gin.H{"offset": syntaxErr.Offset})
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest,
+			fmt.Sprintf("invalid JSON body: %s must be %s", typeErr.Field, jsonTypeName(typeErr.Type.Kind())),
+			gin.H{"field": typeErr.Field})
+	case errors.As(err, &typeErr):
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid JSON body: expected "+jsonTypeName(typeErr.Type.Kind()))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid JSON body: unknown field "+field, gin.H{"field": field})
+	case errors.As(err, &fieldErrs):
+		respondFieldErrors(c, fieldErrs)
+	default:
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid request body")
+	}
+}
+
+// respondFieldErrors answers failed binding tags with one localized message
+// per field, joined in the message and keyed by field in details.fields.
+func respondFieldErrors(c *gin.Context, fieldErrs validator.ValidationErrors) {
+	messages := make([]string, 0, len(fieldErrs))
+	fields := gin.H{}
+	for _, fieldErr := range fieldErrs {
+		key := msgFieldInvalid
+		if fieldErr.Tag() == "required" {
+			key = msgFieldRequired
+		}
+		message := localize(c, newValidationError(key, fieldErr.Field()))
+		messages = append(messages, message)
+		fields[fieldErr.Field()] = message
+	}
+	respondErrorWith(c, http.StatusBadRequest, errCodeValidation, strings.Join(messages, "; "), gin.H{"fields": fields})
+}
+
+// Binding errors name fields as clients send them, by their JSON name.
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// jsonTypeName describes a Go kind in JSON terms for error messages.
+func jsonTypeName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
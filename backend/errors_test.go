@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorResponseIncludesRequestID(t *testing.T) {
+	router := setupTestRouter()
+	config().App.ErrorRequestID = true
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/99999", nil)
+	req.Header.Set("X-Request-ID", "trace-abc")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 404, w.Code)
+	var body APIError
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, errCodeNotFound, body.Code)
+	assert.Equal(t, "Task not found", body.Message)
+	assert.Equal(t, "trace-abc", body.RequestID)
+}
+
+func TestErrorResponseWithoutRequestID(t *testing.T) {
+	router := setupTestRouter()
+	config().App.ErrorRequestID = false
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/99999", nil)
+	router.ServeHTTP(w, req)
+
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.NotContains(t, body, "request_id")
+}
+
+func TestServerErrorIsLoggedWithRequestID(t *testing.T) {
+	router := setupTestRouter()
+	logs := captureLogs(t)
+	config().Security.AuthMode = "bogus"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks", nil)
+	req.Header.Set("X-Request-ID", "trace-500")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 500, w.Code)
+	assert.Contains(t, logs.String(), `"msg":"request failed","request_id":"trace-500"`)
+}
+
+func TestInternalErrorsAreNotLeaked(t *testing.T) {
+	router := setupTestRouter()
+	logs := captureLogs(t)
+
+	_, err := db().Exec("DROP TABLE tasks")
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 500, w.Code)
+	var body APIError
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, errCodeInternal, body.Code)
+	assert.Equal(t, "internal server error", body.Message)
+	assert.NotContains(t, w.Body.String(), "no such table")
+	assert.Contains(t, logs.String(), "no such table: tasks")
+}
+
+func TestMalformedJSONBodies(t *testing.T) {
+	router := setupTestRouter()
+
+	send := func(method, path, body string) APIError {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code, body)
+
+		var apiErr APIError
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+		assert.Equal(t, errCodeInvalidRequest, apiErr.Code)
+		assert.NotContains(t, apiErr.Message, "json:", "parser internals leaked")
+		assert.NotContains(t, apiErr.Message, "Go struct")
+		return apiErr
+	}
+
+	for _, method := range []string{"POST", "PUT"} {
+		path := "/api/v1/tasks"
+		if method == "PUT" {
+			path += "/1"
+		}
+
+		assert.Equal(t, "invalid JSON body: unexpected end of input", send(method, path, `{"title":"trunc`).Message)
+		assert.Equal(t, "invalid JSON body", send(method, path, `{"title": nope}`).Message)
+		assert.Equal(t, "request body is empty", send(method, path, ``).Message)
+
+		wrongType := send(method, path, `{"title": 42}`)
+		assert.Equal(t, "invalid JSON body: title must be a string", wrongType.Message)
+		assert.Equal(t, map[string]interface{}{"field": "title"}, wrongType.Details)
+	}
+
+	assert.Equal(t, "invalid JSON body: expected an array", send("POST", "/api/v1/tasks/batch", `{"title":"x"}`).Message)
+}
+
+func TestBindingErrorsNameFields(t *testing.T) {
+	router := setupTestRouter()
+
+	send := func(path, body, language string) (int, APIError) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", language)
+		router.ServeHTTP(w, req)
+
+		var apiErr APIError
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+		return w.Code, apiErr
+	}
+
+	code, apiErr := send("/api/v1/auth/login", `{"username":"alice"}`, "en")
+	assert.Equal(t, 400, code)
+	assert.Equal(t, errCodeValidation, apiErr.Code)
+	assert.Equal(t, "password is required", apiErr.Message)
+	assert.Equal(t, map[string]interface{}{"fields": map[string]interface{}{"password": "password is required"}}, apiErr.Details)
+
+	_, apiErr = send("/api/v1/auth/login", `{}`, "es")
+	assert.Equal(t, "username es obligatorio; password es obligatorio", apiErr.Message)
+
+	// Anything else the decoder rejects gets a generic message.
+	code, apiErr = send("/api/v1/tasks", `{"title":"x","created_at":"last tuesday"}`, "en")
+	assert.Equal(t, 400, code)
+	assert.Equal(t, errCodeInvalidRequest, apiErr.Code)
+	assert.Equal(t, "invalid request body", apiErr.Message)
+}
+
+func TestRejectUnknownFields(t *testing.T) {
+	setupTestRouter()
+	config().App.RejectUnknownFields = true
+	// The decoder setting is applied when the router is built.
+	router := setupRouter()
+	defer func() { binding.EnableDecoderDisallowUnknownFields = false }()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", strings.NewReader(`{"title":"x","colour":"red"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "unknown field colour")
+}
+
+func TestPanicsAnswerWithJSON(t *testing.T) {
+	router := setupTestRouter()
+	logs := captureLogs(t)
+	config().App.ErrorRequestID = true
+	router.GET("/api/v1/panic", func(c *gin.Context) {
+		panic("secret detail")
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/panic", nil)
+	req.Header.Set("X-Request-ID", "trace-panic")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 500, w.Code)
+	var body APIError
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, APIError{Code: errCodeInternal, Message: "internal server error", RequestID: "trace-panic"}, body)
+	assert.NotContains(t, w.Body.String(), "secret detail")
+	assert.Contains(t, logs.String(), `"msg":"panic recovered","request_id":"trace-panic"`)
+	assert.Contains(t, logs.String(), "secret detail")
+	assert.Contains(t, logs.String(), `"stack":"goroutine `)
+}
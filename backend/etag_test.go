@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTasksCollectionETag(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks?status=pending", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// Unchanged collection
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks?status=pending", nil)
+	req.Header.Set("If-None-Match", etag)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 304, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// Editing a task in the filter set changes the tag
+	task := listTestTasks(t, router, "?status=pending")[0]
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/v1/tasks/"+strconv.Itoa(task.ID), bytes.NewBufferString(`{"title":"Renamed","status":"pending","version":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks?status=pending", nil)
+	req.Header.Set("If-None-Match", etag)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+}
+
+func TestETagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`W/"abc"`, `W/"abc"`))
+	assert.True(t, etagMatches(`"abc"`, `W/"abc"`))
+	assert.True(t, etagMatches(`"x", W/"abc"`, `W/"abc"`))
+	assert.True(t, etagMatches(`*`, `W/"abc"`))
+	assert.False(t, etagMatches(``, `W/"abc"`))
+	assert.False(t, etagMatches(`W/"abd"`, `W/"abc"`))
+}
+
+func TestGetTaskETag(t *testing.T) {
+	router := setupTestRouter()
+	task := createTestTask(t, router, Task{Title: "Cache me"})
+	path := "/api/v1/tasks/" + strconv.Itoa(task.ID)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	assert.Equal(t, 200, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag, "a strong tag")
+	assert.Contains(t, w.Body.String(), "Cache me")
+	assert.Equal(t, etag, get("").Header().Get("ETag"), "the tag is stable")
+
+	w = get(etag)
+	assert.Equal(t, 304, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", path, bytes.NewBufferString(`{"title":"Changed","version":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	w = get(etag)
+	assert.Equal(t, 200, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), "Changed")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Task lifecycle events, as sent to webhooks and stream subscribers.
+const (
+	eventTaskCreated = "task.created"
+	eventTaskUpdated = "task.updated"
+	eventTaskDeleted = "task.deleted"
+)
+
+// subscriberBuffer is how many events a stream client may fall behind before
+// it is dropped.
+const subscriberBuffer = 64
+
+// TaskEvent describes a committed change to a task.
+type TaskEvent struct {
+	Event      string `json:"event"`
+	Task       Task   `json:"task"`
+	OccurredAt string `json:"occurred_at"`
+}
+
+// publishTaskEvent announces a change to a task. Handlers call it only after
+// the change is committed; webhook deliveries are stored and sent in the
+// background, so publishing never blocks or fails the request.
+func publishTaskEvent(event string, task Task) {
+	e := TaskEvent{
+		Event:      event,
+		Task:       task,
+		OccurredAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	webhooks.enqueue(e)
+	taskEvents.publish(e)
+}
+
+// taskEvents fans task events out to the real-time stream endpoints.
+var taskEvents = newEventHub()
+
+// eventSubscriber receives events on a buffered channel. The hub closes the
+// channel when the subscriber falls too far behind.
+type eventSubscriber struct {
+	// user limits delivery to that user's tasks, matching ownerScope; empty
+	// means every task.
+	user   string
+	events chan TaskEvent
+}
+
+// eventHub is an in-process pub/sub hub. Publishing never blocks: a
+// subscriber whose buffer is full is unsubscribed and its channel closed so
+// one slow client can't hold up the handlers that publish.
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[*eventSubscriber]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subscribers: make(map[*eventSubscriber]struct{})}
+}
+
+func (h *eventHub) subscribe(user string) *eventSubscriber {
+	sub := &eventSubscriber{user: user, events: make(chan TaskEvent, subscriberBuffer)}
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// unsubscribe removes sub and closes its channel; it is a no-op if the hub
+// already dropped it.
+func (h *eventHub) unsubscribe(sub *eventSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+func (h *eventHub) publish(event TaskEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if sub.user != "" && (event.Task.OwnerID == nil || *event.Task.OwnerID != sub.user) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(h.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// taskExportFormatVersion is bumped whenever TaskExport changes shape so
+// importers can reject documents they don't understand. Version 2 added
+// comments and subtasks; a version 1 document is a version 2 one without
+// them, so both are accepted.
+const taskExportFormatVersion = 2
+
+// TaskExport is a self-contained document describing a single task with its
+// comments and subtasks, suitable for re-importing it on another instance.
+type TaskExport struct {
+	FormatVersion int    `json:"format_version"`
+	ExportedAt    string `json:"exported_at"`
+	Source        string `json:"source"`
+	ExportedTask
+}
+
+// ExportedTask is a task in an export document with its comments, oldest
+// first, and its live subtasks, each exported the same way.
+type ExportedTask struct {
+	Task     Task           `json:"task"`
+	Comments []Comment      `json:"comments"`
+	Subtasks []ExportedTask `json:"subtasks"`
+}
+
+func exportTask(c *gin.Context) {
+	id, ok := taskIDParam(c)
+	if !ok {
+		return
+	}
+
+	task, err := lookupTask(c, db(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, errCodeNotFound, "Task not found")
+		} else {
+			respondInternalError(c, err)
+		}
+		return
+	}
+	exported, err := exportTaskTree(c, task)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, TaskExport{
+		FormatVersion: taskExportFormatVersion,
+		ExportedAt:    time.Now().UTC().Format(time.RFC3339),
+		Source:        config().App.Name,
+		ExportedTask:  exported,
+	})
+}
+
+// exportTaskTree loads the tags and comments of task and, recursively, its
+// subtasks the caller can see.
+func exportTaskTree(c *gin.Context, task Task) (ExportedTask, error) {
+	ctx := c.Request.Context()
+	exported := ExportedTask{Task: task, Comments: []Comment{}, Subtasks: []ExportedTask{}}
+	if err := taskTags(ctx, db(), &exported.Task); err != nil {
+		return exported, err
+	}
+
+	rows, err := db().QueryContext(ctx, "SELECT "+commentColumns+" FROM comments WHERE task_id = ? ORDER BY id", task.ID)
+	if err != nil {
+		return exported, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return exported, err
+		}
+		exported.Comments = append(exported.Comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return exported, err
+	}
+
+	scope, args := ownerScope(c)
+	subtasks, err := queryTasks(ctx, db(), "SELECT "+taskColumns+" FROM tasks WHERE parent_id = ? AND "+notDeletedPredicate+scope+" ORDER BY id", append([]interface{}{task.ID}, args...)...)
+	if err != nil {
+		return exported, err
+	}
+	for _, subtask := range subtasks {
+		child, err := exportTaskTree(c, subtask)
+		if err != nil {
+			return exported, err
+		}
+		exported.Subtasks = append(exported.Subtasks, child)
+	}
+	return exported, nil
+}
+
+// importTask recreates an exported task, its comments and its subtasks under
+// new ids in one transaction, keeping their original creation, modification
+// and completion times. Multipart uploads are CSV imports instead.
+func importTask(c *gin.Context) {
+	ctx := c.Request.Context()
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		importTasksCSV(c)
+		return
+	}
+
+	var doc TaskExport
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if doc.FormatVersion < 1 || doc.FormatVersion > taskExportFormatVersion {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "unsupported export format version")
+		return
+	}
+
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var imported []Task
+	if err := importTaskTree(c, tx, &doc.ExportedTask, nil, &imported); err != nil {
+		respondWriteError(c, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	for _, task := range imported {
+		publishTaskEvent(eventTaskCreated, task)
+	}
+
+	c.JSON(http.StatusCreated, imported[0])
+}
+
+// importTaskTree stores exported.Task under parentID, then its comments and
+// subtasks, appending every task it creates to imported.
+func importTaskTree(c *gin.Context, tx dbtx, exported *ExportedTask, parentID *int, imported *[]Task) error {
+	ctx := c.Request.Context()
+	task := exported.Task
+	// The parent id refers to the exporting database, not this one.
+	task.ParentID = parentID
+	if err := validateTask(&task); err != nil {
+		return err
+	}
+	if task.Status == "" {
+		task.Status = "pending"
+	}
+
+	exportedCreatedAt, exportedUpdatedAt, exportedCompletedAt := task.CreatedAt, task.UpdatedAt, task.CompletedAt
+	if err := insertTask(ctx, tx, &task, currentUser(c)); err != nil {
+		return err
+	}
+
+	if !exportedCreatedAt.IsZero() {
+		if exportedUpdatedAt.IsZero() {
+			exportedUpdatedAt = exportedCreatedAt
+		}
+		exportedCreatedAt.Time, exportedUpdatedAt.Time = exportedCreatedAt.UTC(), exportedUpdatedAt.UTC()
+		if _, err := tx.ExecContext(ctx, "UPDATE tasks SET created_at = ?, updated_at = ? WHERE id = ?", exportedCreatedAt.Time, exportedUpdatedAt.Time, task.ID); err != nil {
+			return err
+		}
+		task.CreatedAt, task.UpdatedAt = exportedCreatedAt, exportedUpdatedAt
+	}
+	if exportedCompletedAt != nil && task.Status == "completed" {
+		exportedCompletedAt.Time = exportedCompletedAt.UTC()
+		if _, err := tx.ExecContext(ctx, "UPDATE tasks SET completed_at = ? WHERE id = ?", exportedCompletedAt.Time, task.ID); err != nil {
+			return err
+		}
+		task.CompletedAt = exportedCompletedAt
+	}
+	*imported = append(*imported, task)
+
+	for _, comment := range exported.Comments {
+		if err := validateComment(&comment); err != nil {
+			return err
+		}
+		// Comments keep their author and time; one without a readable time
+		// is stamped now.
+		createdAt := time.Now().UTC()
+		if at, err := parseTimestamp(comment.CreatedAt); err == nil {
+			createdAt = at.UTC()
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO comments (task_id, body, author, created_at) VALUES (?, ?, ?, ?)", task.ID, comment.Body, comment.Author, createdAt); err != nil {
+			return err
+		}
+	}
+
+	for i := range exported.Subtasks {
+		if err := importTaskTree(c, tx, &exported.Subtasks[i], &task.ID, imported); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImportTask(t *testing.T) {
+	router := setupTestRouter()
+
+	original := createTestTask(t, router, Task{Title: "Portable", Description: "Moves between instances", Status: "in_progress"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/"+strconv.Itoa(original.ID)+"/export", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	var doc TaskExport
+	err := json.Unmarshal(w.Body.Bytes(), &doc)
+	assert.NoError(t, err)
+	assert.Equal(t, taskExportFormatVersion, doc.FormatVersion)
+	assert.Equal(t, original.Title, doc.Task.Title)
+
+	// Import into a fresh instance
+	router = setupTestRouter()
+
+	body, _ := json.Marshal(doc)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/tasks/import", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 201, w.Code)
+	var imported Task
+	json.Unmarshal(w.Body.Bytes(), &imported)
+	assert.Equal(t, original.Title, imported.Title)
+	assert.Equal(t, original.Description, imported.Description)
+	assert.Equal(t, original.Status, imported.Status)
+	assert.Equal(t, original.CreatedAt, imported.CreatedAt)
+	assert.Equal(t, original.UpdatedAt, imported.UpdatedAt)
+}
+
+func TestExportImportTaskWithCommentsAndSubtasks(t *testing.T) {
+	router := setupTestRouter()
+
+	parent := createTestTask(t, router, Task{Title: "Release", Tags: []string{"launch"}})
+	child := createTestTask(t, router, Task{Title: "Write notes", ParentID: &parent.ID})
+	createTestTask(t, router, Task{Title: "Proofread notes", ParentID: &child.ID, Status: "completed"})
+	for taskID, body := range map[int]string{parent.ID: "Ship on Friday", child.ID: "Draft is in the wiki"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/tasks/"+strconv.Itoa(taskID)+"/comments", bytes.NewBufferString(`{"body":"`+body+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 201, w.Code, w.Body.String())
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/"+strconv.Itoa(parent.ID)+"/export", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	exported := w.Body.Bytes()
+
+	var doc TaskExport
+	assert.NoError(t, json.Unmarshal(exported, &doc))
+	assert.Equal(t, 2, doc.FormatVersion)
+	assert.Len(t, doc.Comments, 1)
+	if assert.Len(t, doc.Subtasks, 1) {
+		assert.Equal(t, "Draft is in the wiki", doc.Subtasks[0].Comments[0].Body)
+		assert.Len(t, doc.Subtasks[0].Subtasks, 1)
+	}
+
+	// Import into a fresh instance and export again: apart from ids and the
+	// export time, the documents match.
+	router = setupTestRouter()
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/tasks/import", bytes.NewBuffer(exported))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code, w.Body.String())
+	var imported Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &imported))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks/"+strconv.Itoa(imported.ID)+"/export", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var roundTrip TaskExport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &roundTrip))
+	assert.Equal(t, withoutIDs(doc.ExportedTask), withoutIDs(roundTrip.ExportedTask))
+}
+
+// withoutIDs clears what an import is expected to change: ids, links between
+// them and the versions of the new rows.
+func withoutIDs(exported ExportedTask) ExportedTask {
+	exported.Task.ID, exported.Task.ParentID, exported.Task.Version = 0, nil, 0
+	for i := range exported.Comments {
+		exported.Comments[i].ID, exported.Comments[i].TaskID = 0, 0
+	}
+	for i := range exported.Subtasks {
+		exported.Subtasks[i] = withoutIDs(exported.Subtasks[i])
+	}
+	return exported
+}
+
+func TestExportTaskNotFound(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/99999/export", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestImportTaskUnsupportedVersion(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/import", bytes.NewBufferString(`{"format_version":99,"task":{"title":"x"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+}
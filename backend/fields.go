@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// taskFields holds the JSON names of Task's fields, which are the only
+// names ?fields= accepts.
+var taskFields = jsonFieldNames(reflect.TypeOf(Task{}))
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// requestedFields parses a ?fields=id,title sparse fieldset. It returns nil
+// when the parameter is absent, meaning every field.
+func requestedFields(c *gin.Context) ([]string, error) {
+	raw, ok := c.GetQuery("fields")
+	if !ok {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !taskFields[name] {
+			return nil, newValidationError(msgFieldUnknown, name, strings.Join(sortedTaskFields(), ", "))
+		}
+		fields = append(fields, name)
+	}
+	if len(fields) == 0 {
+		return nil, newValidationError(msgFieldsEmpty)
+	}
+	return fields, nil
+}
+
+func sortedTaskFields() []string {
+	names := make([]string, 0, len(taskFields))
+	for name := range taskFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectFields returns task as a JSON object holding only fields. Fields the
+// task leaves out through omitempty stay out.
+func selectFields(task Task, fields []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, name := range fields {
+		if value, ok := all[name]; ok {
+			selected[name] = value
+		}
+	}
+	return selected, nil
+}
+
+// marshalTasks encodes tasks, restricted to fields when they are given.
+func marshalTasks(tasks []Task, fields []string) ([]byte, error) {
+	if fields == nil {
+		return json.Marshal(tasks)
+	}
+	selected := make([]map[string]json.RawMessage, len(tasks))
+	for i, task := range tasks {
+		var err error
+		if selected[i], err = selectFields(task, fields); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(selected)
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/kinds"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/graphql-go/graphql/language/visitor"
+)
+
+// GraphQLRequest is the body of POST /graphql.
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// graphQLError is a resolver failure reported with the same code the REST
+// endpoints would use, under extensions.code.
+type graphQLError struct {
+	code    string
+	message string
+	details interface{}
+}
+
+func (e *graphQLError) Error() string {
+	return e.message
+}
+
+func (e *graphQLError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.code}
+	if e.details != nil {
+		extensions["details"] = e.details
+	}
+	return extensions
+}
+
+type ginContextKey struct{}
+
+// resolverContext recovers the request a resolver runs for, so resolvers
+// share the REST handlers' scoping, authorship and language.
+func resolverContext(p graphql.ResolveParams) *gin.Context {
+	return p.Context.Value(ginContextKey{}).(*gin.Context)
+}
+
+// resolverError converts err the way respondWriteError would, so a GraphQL
+// client sees the same codes and localized messages as a REST client.
+func resolverError(c *gin.Context, err error) error {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		return &graphQLError{code: reqErr.code, message: reqErr.message, details: reqErr.details}
+	case isValidationError(err):
+		return &graphQLError{code: errCodeValidation, message: localize(c, err)}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &graphQLError{code: errCodeTimeout, message: "request timed out"}
+	default:
+		requestLogger(c).Error("internal error", "method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
+		return &graphQLError{code: errCodeInternal, message: "internal server error"}
+	}
+}
+
+// timestampField resolves a Timestamp or *Timestamp field of a task.
+func timestampField(get func(Task) *Timestamp) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.String,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if at := get(p.Source.(Task)); at != nil {
+				return at.String(), nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+// Field names follow the JSON representation, so the default resolver reads
+// them straight off Task.
+var taskType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Task",
+	Fields: graphql.Fields{
+		"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"title":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"description":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"priority":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"due_date":     &graphql.Field{Type: graphql.String},
+		"created_at":   timestampField(func(t Task) *Timestamp { return &t.CreatedAt }),
+		"updated_at":   timestampField(func(t Task) *Timestamp { return &t.UpdatedAt }),
+		"completed_at": timestampField(func(t Task) *Timestamp { return t.CompletedAt }),
+		"created_by":   &graphql.Field{Type: graphql.String},
+		"updated_by":   &graphql.Field{Type: graphql.String},
+		"owner_id":     &graphql.Field{Type: graphql.String},
+		"assignee":     &graphql.Field{Type: graphql.String},
+		"archived":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"tags":         &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		"recurrence":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"version":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"parent_id":    &graphql.Field{Type: graphql.Int},
+		"blocked_by":   &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.Int))},
+		"position":     &graphql.Field{Type: graphql.Int},
+	},
+})
+
+// taskInputType holds the writable fields, as accepted by POST and PUT
+// /tasks.
+var taskInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "TaskInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"title":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"status":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"priority":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"due_date":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"assignee":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"parent_id":   &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"recurrence":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"tags":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+	},
+})
+
+var graphQLSchema = mustGraphQLSchema()
+
+func mustGraphQLSchema() graphql.Schema {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"tasks": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taskType))),
+					Description: "Unarchived tasks, oldest first, like GET /tasks.",
+					Args: graphql.FieldConfigArgument{
+						"status": &graphql.ArgumentConfig{Type: graphql.String},
+						"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageLimit},
+					},
+					Resolve: resolveTasks,
+				},
+				"task": &graphql.Field{
+					Type:        taskType,
+					Description: "A single task with its tags and blockers, or null if there is no such task.",
+					Args: graphql.FieldConfigArgument{
+						"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					},
+					Resolve: resolveTask,
+				},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"createTask": &graphql.Field{
+					Type: graphql.NewNonNull(taskType),
+					Args: graphql.FieldConfigArgument{
+						"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(taskInputType)},
+					},
+					Resolve: resolveCreateTask,
+				},
+				"updateTask": &graphql.Field{
+					Type:        graphql.NewNonNull(taskType),
+					Description: "Replaces a task's fields if it is still at version, like PUT /tasks/{id}.",
+					Args: graphql.FieldConfigArgument{
+						"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+						"version": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+						"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(taskInputType)},
+					},
+					Resolve: resolveUpdateTask,
+				},
+				"deleteTask": &graphql.Field{
+					Type: graphql.NewNonNull(taskType),
+					Args: graphql.FieldConfigArgument{
+						"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					},
+					Resolve: resolveDeleteTask,
+				},
+			},
+		}),
+	})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+func resolveTasks(p graphql.ResolveParams) (interface{}, error) {
+	c := resolverContext(p)
+	conditions := []string{notDeletedPredicate, "archived = ?"}
+	args := []interface{}{false}
+	if status, ok := p.Args["status"].(string); ok {
+		status = normalizeStatus(status)
+		if !validStatuses[status] {
+			return nil, resolverError(c, newValidationError(msgStatusInvalid))
+		}
+		conditions = append(conditions, "status = ?")
+		args = append(args, status)
+	}
+	limit, _ := p.Args["limit"].(int)
+	if limit <= 0 || limit > maxPageLimit {
+		return nil, resolverError(c, newValidationError(msgLimitInvalid, maxPageLimit))
+	}
+
+	scope, scopeArgs := ownerScope(c)
+	args = append(append(args, scopeArgs...), limit)
+	tasks, err := queryTasks(p.Context, db(), "SELECT "+taskColumns+" FROM tasks WHERE "+strings.Join(conditions, " AND ")+scope+" ORDER BY id LIMIT ?", args...)
+	if err != nil {
+		return nil, resolverError(c, err)
+	}
+	if err := attachTags(p.Context, db(), tasks); err != nil {
+		return nil, resolverError(c, err)
+	}
+	return tasks, nil
+}
+
+func resolveTask(p graphql.ResolveParams) (interface{}, error) {
+	c := resolverContext(p)
+	id := p.Args["id"].(int)
+	task, err := lookupTask(c, db(), id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolverError(c, err)
+	}
+	if task.BlockedBy, err = blockedBy(p.Context, db(), id); err != nil {
+		return nil, resolverError(c, err)
+	}
+	if err := taskTags(p.Context, db(), &task); err != nil {
+		return nil, resolverError(c, err)
+	}
+	return task, nil
+}
+
+// inputTask decodes a TaskInput argument into a Task and validates it as the
+// REST handlers validate a request body.
+func inputTask(p graphql.ResolveParams) (Task, error) {
+	var task Task
+	data, err := json.Marshal(p.Args["input"])
+	if err != nil {
+		return Task{}, err
+	}
+	if err := json.Unmarshal(data, &task); err != nil {
+		return Task{}, err
+	}
+	return task, validateTask(&task)
+}
+
+func resolveCreateTask(p graphql.ResolveParams) (interface{}, error) {
+	c := resolverContext(p)
+	task, err := inputTask(p)
+	if err != nil {
+		return nil, resolverError(c, err)
+	}
+	if task.Status == "" {
+		task.Status = "pending"
+	}
+
+	tx, err := db().BeginTx(p.Context, nil)
+	if err != nil {
+		return nil, resolverError(c, err)
+	}
+	defer tx.Rollback()
+
+	if err := storeNewTask(c, tx, &task); err != nil {
+		return nil, resolverError(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, resolverError(c, err)
+	}
+	publishTaskEvent(eventTaskCreated, task)
+	return task, nil
+}
+
+func resolveUpdateTask(p graphql.ResolveParams) (interface{}, error) {
+	c := resolverContext(p)
+	task, err := inputTask(p)
+	if err != nil {
+		return nil, resolverError(c, err)
+	}
+	updated, err := replaceTask(c, p.Args["id"].(int), p.Args["version"].(int), task)
+	if err != nil {
+		return nil, resolverError(c, err)
+	}
+	return updated, nil
+}
+
+func resolveDeleteTask(p graphql.ResolveParams) (interface{}, error) {
+	c := resolverContext(p)
+	task, err := softDeleteTask(c, db(), p.Args["id"].(int))
+	if err != nil {
+		return nil, resolverError(c, err)
+	}
+	publishTaskEvent(eventTaskDeleted, task)
+	return task, nil
+}
+
+// noIntrospectionRule rejects __schema and __type queries. It applies in
+// production, where the playground is also hidden, so the API's full shape
+// isn't handed to anyone who asks.
+func noIntrospectionRule(context *graphql.ValidationContext) *graphql.ValidationRuleInstance {
+	return &graphql.ValidationRuleInstance{
+		VisitorOpts: &visitor.VisitorOptions{
+			KindFuncMap: map[string]visitor.NamedVisitFuncs{
+				kinds.Field: {
+					Kind: func(p visitor.VisitFuncParams) (string, interface{}) {
+						if node, ok := p.Node.(*ast.Field); ok && node.Name != nil {
+							if name := node.Name.Value; name == "__schema" || name == "__type" {
+								err := &graphQLError{code: errCodeForbidden, message: "introspection is disabled in production"}
+								context.ReportError(graphql.NewLocatedError(err, []ast.Node{node}))
+							}
+						}
+						return visitor.ActionNoChange, nil
+					},
+				},
+			},
+		},
+	}
+}
+
+// serveGraphQL executes a query or mutation. As with any GraphQL server,
+// failures inside an operation are reported in the errors list of a 200
+// response; only a body that isn't a GraphQL request gets a 4xx.
+func serveGraphQL(c *gin.Context) {
+	var req GraphQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "query is required")
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		c.JSON(http.StatusOK, graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	rules := graphql.SpecifiedRules
+	if config().App.Environment == "production" {
+		rules = append(append([]graphql.ValidationRuleFn{}, rules...), noIntrospectionRule)
+	}
+	if validation := graphql.ValidateDocument(&graphQLSchema, doc, rules); !validation.IsValid {
+		c.JSON(http.StatusOK, graphql.Result{Errors: validation.Errors})
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        graphQLSchema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       context.WithValue(c.Request.Context(), ginContextKey{}, c),
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+// graphiQLPage loads GraphiQL from a CDN and points it at POST /graphql.
+const graphiQLPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>TaskHub GraphQL</title>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css">
+</head>
+<body style="margin: 0">
+  <div id="graphiql" style="height: 100vh"></div>
+  <script src="https://unpkg.com/react@18/umd/react.production.min.js" crossorigin></script>
+  <script src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js" crossorigin></script>
+  <script src="https://unpkg.com/graphiql@3/graphiql.min.js" crossorigin></script>
+  <script>
+    const fetcher = GraphiQL.createFetcher({url: "graphql"});
+    ReactDOM.createRoot(document.getElementById("graphiql")).render(React.createElement(GraphiQL, {fetcher}));
+  </script>
+</body>
+</html>
+`
+
+func getGraphQLPlayground(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(graphiQLPage))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type graphQLTestResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func postGraphQL(t *testing.T, router *gin.Engine, query string, variables map[string]interface{}) graphQLTestResponse {
+	t.Helper()
+
+	body, _ := json.Marshal(GraphQLRequest{Query: query, Variables: variables})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code, w.Body.String())
+
+	var response graphQLTestResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+func TestGraphQLQueries(t *testing.T) {
+	router := setupTestRouter()
+	pending := createTestTask(t, router, Task{Title: "Pending", Tags: []string{"ops"}})
+	createTestTask(t, router, Task{Title: "Started", Status: "in_progress"})
+
+	response := postGraphQL(t, router, `{ tasks(status: "pending") { id status tags } }`, nil)
+	assert.Empty(t, response.Errors)
+	var tasks []Task
+	assert.NoError(t, json.Unmarshal(response.Data["tasks"], &tasks))
+	found := false
+	for _, task := range tasks {
+		assert.Equal(t, "pending", task.Status)
+		if task.ID == pending.ID {
+			found = true
+			assert.Equal(t, []string{"ops"}, task.Tags)
+		}
+	}
+	assert.True(t, found)
+
+	response = postGraphQL(t, router, `query($id: Int!) { task(id: $id) { title status created_at completed_at } }`, map[string]interface{}{"id": pending.ID})
+	assert.Empty(t, response.Errors)
+	var task map[string]interface{}
+	assert.NoError(t, json.Unmarshal(response.Data["task"], &task))
+	assert.Equal(t, "Pending", task["title"])
+	assert.Equal(t, pending.CreatedAt.String(), task["created_at"])
+	assert.Nil(t, task["completed_at"])
+	assert.NotContains(t, task, "description", "only the selected fields are returned")
+
+	response = postGraphQL(t, router, `{ task(id: 99999) { id } }`, nil)
+	assert.Empty(t, response.Errors)
+	assert.Equal(t, "null", string(response.Data["task"]))
+
+	response = postGraphQL(t, router, `{ tasks(limit: 0) { id } }`, nil)
+	if assert.Len(t, response.Errors, 1) {
+		assert.Equal(t, errCodeValidation, response.Errors[0].Extensions["code"])
+	}
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	router := setupTestRouter()
+
+	response := postGraphQL(t, router, `mutation { createTask(input: {title: "  Ship it  ", priority: "HIGH"}) { id title priority status version } }`, nil)
+	assert.Empty(t, response.Errors)
+	var created Task
+	assert.NoError(t, json.Unmarshal(response.Data["createTask"], &created))
+	assert.Equal(t, "Ship it", created.Title, "input goes through validateTask")
+	assert.Equal(t, "high", created.Priority)
+	assert.Equal(t, "pending", created.Status)
+	assert.Equal(t, created.Title, getTestTask(t, router, created.ID).Title)
+
+	response = postGraphQL(t, router, `mutation { createTask(input: {title: " "}) { id } }`, nil)
+	if assert.Len(t, response.Errors, 1) {
+		assert.Equal(t, errCodeValidation, response.Errors[0].Extensions["code"])
+	}
+
+	update := `mutation($id: Int!, $version: Int!) { updateTask(id: $id, version: $version, input: {title: "Shipped", status: "in_progress"}) { title status version } }`
+	response = postGraphQL(t, router, update, map[string]interface{}{"id": created.ID, "version": created.Version})
+	assert.Empty(t, response.Errors)
+	var updated Task
+	assert.NoError(t, json.Unmarshal(response.Data["updateTask"], &updated))
+	assert.Equal(t, "Shipped", updated.Title)
+	assert.Equal(t, created.Version+1, updated.Version)
+
+	response = postGraphQL(t, router, update, map[string]interface{}{"id": created.ID, "version": created.Version})
+	if assert.Len(t, response.Errors, 1) {
+		assert.Equal(t, errCodeConflict, response.Errors[0].Extensions["code"])
+	}
+
+	response = postGraphQL(t, router, fmt.Sprintf(`mutation { deleteTask(id: %d) { id } }`, created.ID), nil)
+	assert.Empty(t, response.Errors)
+	response = postGraphQL(t, router, fmt.Sprintf(`mutation { deleteTask(id: %d) { id } }`, created.ID), nil)
+	if assert.Len(t, response.Errors, 1) {
+		assert.Equal(t, errCodeNotFound, response.Errors[0].Extensions["code"])
+	}
+}
+
+func TestGraphQLIntrospection(t *testing.T) {
+	router := setupTestRouter()
+	introspect := `{ __schema { queryType { name } } }`
+
+	response := postGraphQL(t, router, introspect, nil)
+	assert.Empty(t, response.Errors)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/graphql", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "graphiql")
+
+	config().App.Environment = "production"
+	defer func() { config().App.Environment = "test" }()
+
+	response = postGraphQL(t, router, introspect, nil)
+	if assert.Len(t, response.Errors, 1) {
+		assert.Equal(t, errCodeForbidden, response.Errors[0].Extensions["code"])
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
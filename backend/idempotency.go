@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader          = "Idempotency-Key"
+	idempotencyReplayedHeader     = "Idempotent-Replayed"
+	maxIdempotencyKeyLength       = 255
+	defaultIdempotencyWindowHours = 24
+)
+
+func idempotencyWindow() time.Duration {
+	if config().App.IdempotencyWindowHours > 0 {
+		return time.Duration(config().App.IdempotencyWindowHours) * time.Hour
+	}
+	return defaultIdempotencyWindowHours * time.Hour
+}
+
+// idempotencyKey returns the trimmed Idempotency-Key header, or "" when the
+// client didn't send one.
+func idempotencyKey(c *gin.Context) (string, error) {
+	key := strings.TrimSpace(c.GetHeader(idempotencyKeyHeader))
+	if len(key) > maxIdempotencyKeyLength {
+		return "", newValidationError(msgIdempotencyKeyTooLong, maxIdempotencyKeyLength)
+	}
+	return key, nil
+}
+
+// requestFingerprint hashes the validated task so a key reused for a
+// different request is caught, while formatting differences are not.
+func requestFingerprint(task Task) (string, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func idempotencyCutoff() string {
+	return time.Now().UTC().Add(-idempotencyWindow()).Format(time.RFC3339)
+}
+
+// replayIdempotent answers with the stored response when key was already
+// used by the caller within the window, and reports whether it did. A key
+// reused with a different request gets 409.
+func replayIdempotent(c *gin.Context, key, fingerprint string) bool {
+	var storedFingerprint, response string
+	err := db().QueryRowContext(c.Request.Context(),
+		"SELECT request_hash, response FROM idempotency_keys WHERE idempotency_key = ? AND owner = ? AND "+timestampCompare("created_at", ">="),
+		key, currentUser(c), idempotencyCutoff()).Scan(&storedFingerprint, &response)
+	if err == sql.ErrNoRows {
+		return false
+	}
+	if err != nil {
+		respondInternalError(c, err)
+		return true
+	}
+
+	if storedFingerprint != fingerprint {
+		respondError(c, http.StatusConflict, errCodeConflict, "Idempotency-Key was already used for a different request")
+		return true
+	}
+	c.Header(idempotencyReplayedHeader, "true")
+	c.Data(http.StatusCreated, "application/json; charset=utf-8", []byte(response))
+	return true
+}
+
+// storeIdempotencyKey records the response for key as part of the creating
+// transaction and drops the caller's expired keys. It reports false when a
+// concurrent request with the same key got there first.
+func storeIdempotencyKey(ctx context.Context, q dbtx, key, owner, fingerprint string, taskID int, response []byte) (bool, error) {
+	if _, err := q.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE owner = ? AND "+timestampCompare("created_at", "<"), owner, idempotencyCutoff()); err != nil {
+		return false, err
+	}
+	result, err := q.ExecContext(ctx, "INSERT INTO idempotency_keys (idempotency_key, owner, request_hash, task_id, response, created_at) VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP) ON CONFLICT (idempotency_key, owner) DO NOTHING",
+		key, owner, fingerprint, taskID, string(response))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func postWithIdempotencyKey(router http.Handler, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyKeyReplaysCreate(t *testing.T) {
+	router := setupTestRouter()
+	before := countTestTasks(t, router, "")
+
+	first := postWithIdempotencyKey(router, "retry-1", `{"title":"Pay invoice"}`)
+	assert.Equal(t, 201, first.Code)
+	assert.Empty(t, first.Header().Get(idempotencyReplayedHeader))
+
+	// Whitespace differences still count as the same request.
+	retry := postWithIdempotencyKey(router, "retry-1", `{ "title": "Pay invoice" }`)
+	assert.Equal(t, 201, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(idempotencyReplayedHeader))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, before+1, countTestTasks(t, router, ""))
+
+	w := postWithIdempotencyKey(router, "retry-1", `{"title":"Something else"}`)
+	assert.Equal(t, 409, w.Code)
+
+	w = postWithIdempotencyKey(router, "retry-2", `{"title":"Pay invoice"}`)
+	assert.Equal(t, 201, w.Code)
+	w = postWithIdempotencyKey(router, "", `{"title":"Pay invoice"}`)
+	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, before+3, countTestTasks(t, router, ""))
+
+	w = postWithIdempotencyKey(router, strings.Repeat("k", maxIdempotencyKeyLength+1), `{"title":"Pay invoice"}`)
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	router := setupTestRouter()
+
+	first := postWithIdempotencyKey(router, "old-key", `{"title":"Water plants"}`)
+	assert.Equal(t, 201, first.Code)
+	_, err := db().Exec("UPDATE idempotency_keys SET created_at = '2000-01-01 00:00:00'")
+	assert.NoError(t, err)
+
+	again := postWithIdempotencyKey(router, "old-key", `{"title":"Water plants"}`)
+	assert.Equal(t, 201, again.Code)
+	assert.Empty(t, again.Header().Get(idempotencyReplayedHeader))
+	assert.NotEqual(t, first.Body.String(), again.Body.String())
+
+	var keys int
+	assert.NoError(t, db().QueryRow("SELECT COUNT(*) FROM idempotency_keys").Scan(&keys))
+	assert.Equal(t, 1, keys, "the expired key is replaced")
+}
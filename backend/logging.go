@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// logger is the application-wide structured logger, configured from the
+// logging section of the config by setupLogging.
+var logger = slog.Default()
+
+// newLogger builds a logger writing to w. format is "json" or "text" (the
+// default); level is one of debug, info (the default), warn or error.
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	switch strings.ToLower(level) {
+	case "debug":
+		lvl = slog.LevelDebug
+	case "", "info":
+		lvl = slog.LevelInfo
+	case "warn", "warning":
+		lvl = slog.LevelWarn
+	case "error":
+		lvl = slog.LevelError
+	default:
+		return nil, fmt.Errorf("unknown log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// switchHandler forwards to a handler that can be replaced while the program
+// runs, so a config reload can change the log level and format without
+// swapping the logger other goroutines already hold. Loggers derived with
+// With or WithGroup keep the handler that was current when they were made.
+type switchHandler struct {
+	current atomic.Pointer[slog.Handler]
+}
+
+func (h *switchHandler) set(handler slog.Handler) { h.current.Store(&handler) }
+func (h *switchHandler) handler() slog.Handler    { return *h.current.Load() }
+
+func (h *switchHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler().Enabled(ctx, level)
+}
+
+func (h *switchHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler().Handle(ctx, r)
+}
+
+func (h *switchHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.handler().WithAttrs(attrs)
+}
+
+func (h *switchHandler) WithGroup(name string) slog.Handler {
+	return h.handler().WithGroup(name)
+}
+
+// logOutput is the handler behind logger once setupLogging has run.
+var logOutput *switchHandler
+
+// setupLogging configures the global logger according to config.Logging and
+// makes it the slog default so library code logging through slog agrees.
+// Calling it again, as a config reload does, switches the output in place.
+func setupLogging() error {
+	cfg := config()
+	l, err := newLogger(os.Stderr, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		return err
+	}
+	if logOutput != nil {
+		logOutput.set(l.Handler())
+		return nil
+	}
+	logOutput = &switchHandler{}
+	logOutput.set(l.Handler())
+	logger = slog.New(logOutput)
+	slog.SetDefault(logger)
+	return nil
+}
+
+// fatal logs msg at error level and exits, replacing log.Fatalf.
+func fatal(msg string, args ...interface{}) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLoggerJSONIsMachineParseable(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := newLogger(&buf, "info", "json")
+	assert.NoError(t, err)
+
+	l.Debug("hidden at info level")
+	l.Info("task created", "id", 7, "title", `quotes " and newlines
+inside`)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 1)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, "task created", entry["msg"])
+	assert.Equal(t, float64(7), entry["id"])
+}
+
+func TestNewLoggerLevels(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := newLogger(&buf, "warn", "text")
+	assert.NoError(t, err)
+
+	l.Info("dropped")
+	l.Warn("kept")
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.Contains(t, buf.String(), "msg=kept")
+
+	_, err = newLogger(&buf, "verbose", "json")
+	assert.Error(t, err)
+	_, err = newLogger(&buf, "info", "xml")
+	assert.Error(t, err)
+}
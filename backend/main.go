@@ -1,65 +1,276 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"gopkg.in/yaml.v2"
 )
 
+// RateLimitConfig throttles each client IP with a token bucket; Burst
+// defaults to RequestsPerMinute. Requests with a valid API key are throttled
+// per key instead: APIKey is the base tier every key gets, and APIKeyLimits
+// overrides it for the keys it names by id (api-key-1 for the first entry of
+// security.api_keys, and so on).
+type RateLimitConfig struct {
+	Enabled           bool                     `yaml:"enabled"`
+	RequestsPerMinute int                      `yaml:"requests_per_minute"`
+	Burst             int                      `yaml:"burst"`
+	APIKey            RateLimitTier            `yaml:"api_key"`
+	APIKeyLimits      map[string]RateLimitTier `yaml:"api_key_limits"`
+}
+
+// RateLimitTier is the limit of one API key. Unset fields fall back to the
+// base tier, and the base tier to the per-IP limit.
+type RateLimitTier struct {
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	Burst             int `yaml:"burst"`
+}
+
+// Config is read from config.yaml at startup and read back through config().
+// On SIGHUP the settings listed in reloadableSettings are re-read.
 type Config struct {
 	App struct {
 		Name        string `yaml:"name"`
 		Version     string `yaml:"version"`
 		Port        int    `yaml:"port"`
 		Environment string `yaml:"environment"`
+		// Host is the interface to listen on, e.g. 127.0.0.1 behind a
+		// reverse proxy; empty means all interfaces.
+		Host string `yaml:"host"`
+		// ShutdownTimeoutSeconds bounds how long in-flight requests get to
+		// finish after SIGINT/SIGTERM; zero means the default.
+		ShutdownTimeoutSeconds int `yaml:"shutdown_timeout_seconds"`
+		// RequestTimeoutSeconds bounds how long a request may spend on the
+		// database before it is canceled with 503; zero means the default.
+		RequestTimeoutSeconds int `yaml:"request_timeout_seconds"`
+		// IdempotencyWindowHours is how long an Idempotency-Key on POST
+		// /tasks is remembered; zero means the default of a day.
+		IdempotencyWindowHours int `yaml:"idempotency_window_hours"`
+		// TitleAutoSuffix makes createTask store a colliding title as
+		// "Title (2)", "Title (3)", ... instead of a silent duplicate.
+		TitleAutoSuffix bool `yaml:"title_auto_suffix"`
+		// Length limits are measured in runes; zero means the default.
+		MaxTitleLength       int `yaml:"max_title_length"`
+		MaxDescriptionLength int `yaml:"max_description_length"`
+		// ErrorRequestID adds the request id to every error response body.
+		ErrorRequestID bool `yaml:"error_request_id"`
+		// Messages overrides or extends the built-in validation message
+		// catalog, keyed by language and then message key.
+		Messages map[string]map[string]string `yaml:"messages"`
+		// MaxBodyBytes caps request bodies; larger ones get 413. Zero means
+		// the default of 1MB.
+		MaxBodyBytes int64 `yaml:"max_body_bytes"`
+		// RejectUnknownFields makes JSON bodies with fields the API doesn't
+		// know fail with 400 instead of being ignored.
+		RejectUnknownFields bool `yaml:"reject_unknown_fields"`
+		// Compression gzips responses of at least MinBytes for clients that
+		// accept it; zero MinBytes means the default of 1KB.
+		Compression struct {
+			Enabled  bool `yaml:"enabled"`
+			MinBytes int  `yaml:"min_bytes"`
+		} `yaml:"compression"`
 	} `yaml:"app"`
 	Database struct {
 		Type           string `yaml:"type"`
 		Path           string `yaml:"path"`
 		MaxConnections int    `yaml:"max_connections"`
 		Timeout        int    `yaml:"timeout"`
+		// ConnectAttempts is how many times startup tries to reach the
+		// database, waiting ConnectBackoffMillis between the first two
+		// attempts and doubling it after each; zero means the defaults.
+		ConnectAttempts      int `yaml:"connect_attempts"`
+		ConnectBackoffMillis int `yaml:"connect_backoff_ms"`
+		// ConnInitStatements run on every new connection, e.g. to set a
+		// search_path on managed databases. Only SET and PRAGMA are allowed.
+		ConnInitStatements []string `yaml:"conn_init_statements"`
+		// Name, Port and SSLMode are used for PostgreSQL together with the
+		// DB_HOST, DB_USER and DB_PASSWORD environment variables.
+		Name    string `yaml:"name"`
+		Port    int    `yaml:"port"`
+		SSLMode string `yaml:"sslmode"`
+		// Seed inserts sample tasks into an empty database; meant for
+		// development only.
+		Seed bool `yaml:"seed"`
+		// Maintenance intervals; zero disables the scheduled job.
+		OptimizeIntervalMinutes int `yaml:"optimize_interval_minutes"`
+		VacuumIntervalHours     int `yaml:"vacuum_interval_hours"`
 	} `yaml:"database"`
 	Logging struct {
 		Level  string `yaml:"level"`
 		Format string `yaml:"format"`
+		// SkipPaths are request paths left out of the access log.
+		SkipPaths []string `yaml:"skip_paths"`
 	} `yaml:"logging"`
 	Security struct {
 		CorsEnabled bool     `yaml:"cors_enabled"`
 		CorsOrigins []string `yaml:"cors_origins"`
+		// AuthMode selects how /tasks is protected: "none", "jwt" or "api_key".
+		AuthMode        string          `yaml:"auth_mode"`
+		JWTSecret       string          `yaml:"jwt_secret"`
+		TokenTTLMinutes int             `yaml:"token_ttl_minutes"`
+		APIKeys         []string        `yaml:"api_keys"`
+		RateLimit       RateLimitConfig `yaml:"rate_limit"`
+		TLS             TLSConfig       `yaml:"tls"`
 	} `yaml:"security"`
+	Admin struct {
+		// Users may call the /admin routes: JWT subjects, or api-key-N for
+		// the Nth entry of security.api_keys. Empty locks everyone out.
+		Users             []string `yaml:"users"`
+		GenerateMaxCount  int      `yaml:"generate_max_count"`
+		GenerateBatchSize int      `yaml:"generate_batch_size"`
+	} `yaml:"admin"`
+	// Workflow tunes the status state machine in statusTransitions.
+	Workflow struct {
+		// ReopenTo lists the statuses a completed task may move back to;
+		// empty means completed is final.
+		ReopenTo []string `yaml:"reopen_to"`
+	} `yaml:"workflow"`
+	// Recurrence controls the job that creates the next occurrence of
+	// completed recurring tasks; zero means the default tick of a minute.
+	Recurrence struct {
+		IntervalSeconds int `yaml:"interval_seconds"`
+	} `yaml:"recurrence"`
+	// Trash controls how long soft-deleted tasks are kept. RetentionDays is
+	// the age DELETE /tasks/trash purges by default, zero meaning 30 days;
+	// a non-zero PurgeIntervalHours also purges on that schedule.
+	Trash struct {
+		RetentionDays      int `yaml:"retention_days"`
+		PurgeIntervalHours int `yaml:"purge_interval_hours"`
+	} `yaml:"trash"`
+	// Webhooks POST task lifecycle events to each URL. Zero sizes and
+	// attempts mean the defaults.
+	Webhooks struct {
+		URLs []string `yaml:"urls"`
+		// Secret signs every payload with HMAC-SHA256 in X-Taskhub-Signature.
+		Secret string `yaml:"secret"`
+		// QueueSize caps the deliveries waiting in webhook_deliveries.
+		QueueSize   int `yaml:"queue_size"`
+		Workers     int `yaml:"workers"`
+		MaxAttempts int `yaml:"max_attempts"`
+	} `yaml:"webhooks"`
 }
 
 type Task struct {
-	ID          int    `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Status      string `json:"status"`
-	CreatedAt   string `json:"created_at"`
+	XMLName     xml.Name  `json:"-" xml:"task"`
+	ID          int       `json:"id" xml:"id"`
+	Title       string    `json:"title" xml:"title"`
+	Description string    `json:"description" xml:"description"`
+	Status      string    `json:"status" xml:"status"`
+	CreatedAt   Timestamp `json:"created_at" xml:"created_at"`
+	// UpdatedAt starts equal to CreatedAt and moves on every modification.
+	UpdatedAt Timestamp `json:"updated_at" xml:"updated_at"`
+	// DueDate is an RFC3339 timestamp, stored and returned in UTC.
+	DueDate  *string `json:"due_date" xml:"due_date"`
+	Priority string  `json:"priority" xml:"priority"`
+	// CreatedBy and UpdatedBy record the authenticated user, when there is one.
+	CreatedBy *string `json:"created_by" xml:"created_by"`
+	UpdatedBy *string `json:"updated_by" xml:"updated_by"`
+	// OwnerID is the user the task belongs to; only they can see or change it.
+	OwnerID *string `json:"owner_id" xml:"owner_id"`
+	// Assignee is who is working on the task, or null if nobody is. It is a
+	// free-form name, independent of the users that authenticate.
+	Assignee *string `json:"assignee" xml:"assignee"`
+	// CompletedAt is when the task last moved to completed, and is cleared
+	// when it moves away again.
+	CompletedAt *Timestamp `json:"completed_at" xml:"completed_at"`
+	// Archived tasks are hidden from listings unless asked for; unlike
+	// deleted ones they are still readable by id.
+	Archived bool `json:"archived" xml:"archived"`
+	// Tags are free-form labels. On update, omitting them keeps the current
+	// ones and an empty list removes them all.
+	Tags []string `json:"tags,omitempty" xml:"tags>tag,omitempty"`
+	// Recurrence is "none" or how often a completed task comes back; see
+	// generateRecurrences.
+	Recurrence string `json:"recurrence" xml:"recurrence"`
+	// Version increases by one on every update; writers must send the version
+	// they last read so concurrent edits aren't silently lost.
+	Version int `json:"version" xml:"version"`
+	// ParentID makes this a subtask of another task; null for top-level tasks.
+	ParentID *int `json:"parent_id" xml:"parent_id"`
+	// BlockedBy lists the tasks this one depends on. It is only filled in by
+	// GET /tasks/:id and a create-if-absent match, and is ignored on writes.
+	BlockedBy []int `json:"blocked_by,omitempty" xml:"blocked_by>id,omitempty"`
+	// Position is the task's place in the custom order set by POST
+	// /tasks/reorder, or null if it was never placed. It is ignored on writes.
+	Position *int `json:"position" xml:"position"`
+	// DeletedAt is when the task was moved to the trash. It is only filled in
+	// by GET /tasks/trash and is ignored on writes.
+	DeletedAt *Timestamp `json:"deleted_at,omitempty" xml:"deleted_at,omitempty"`
+}
+
+// TaskFilter matches tasks on exact field values; nil fields are ignored.
+type TaskFilter struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Status      *string `json:"status"`
+}
+
+type CreateIfAbsentRequest struct {
+	Filter TaskFilter `json:"filter"`
+	Task   Task       `json:"task"`
 }
 
 type HealthResponse struct {
-	Status    string `json:"status"`
-	Version   string `json:"version"`
-	Timestamp string `json:"timestamp"`
+	XMLName   xml.Name `json:"-" xml:"health"`
+	Status    string   `json:"status" xml:"status"`
+	Version   string   `json:"version" xml:"version"`
+	Timestamp string   `json:"timestamp" xml:"timestamp"`
+	// Database is "up" or "down"; liveness doesn't check it and leaves it out.
+	Database string `json:"database,omitempty" xml:"database,omitempty"`
 }
 
-var db *sql.DB
-var config Config
+var (
+	currentDB     atomic.Pointer[sql.DB]
+	currentConfig atomic.Value // *Config
+)
+
+// db returns the open database, or nil before initDatabase has run.
+func db() *sql.DB {
+	return currentDB.Load()
+}
+
+// config returns the current settings. The Config is shared and must not be
+// modified; setConfig replaces it as a whole, so a caller holding one never
+// sees a reload half applied.
+func config() *Config {
+	cfg, _ := currentConfig.Load().(*Config)
+	if cfg == nil {
+		return &Config{}
+	}
+	return cfg
+}
+
+func setConfig(cfg Config) {
+	currentConfig.Store(&cfg)
+}
 
-func loadConfig(configPath string) error {
+func readConfig(configPath string) (Config, error) {
+	var cfg Config
 	data, err := ioutil.ReadFile(configPath)
 	if err != nil {
-		return err
+		return cfg, err
 	}
-	return yaml.Unmarshal(data, &config)
+	return cfg, yaml.Unmarshal(data, &cfg)
 }
 
 func initDatabase() error {
@@ -67,39 +278,122 @@ func initDatabase() error {
 	dbHost := os.Getenv("DB_HOST")
 	dbPassword := os.Getenv("DB_PASSWORD")
 
-	log.Printf("Database config - User: %s, Host: %s, Password: %s",
-		dbUser, dbHost, maskPassword(dbPassword))
+	logger.Debug("database config", "user", dbUser, "host", dbHost, "password", maskPassword(dbPassword))
 
-	var err error
-	db, err = sql.Open("sqlite3", config.Database.Path)
+	conn, err := connectDatabase()
 	if err != nil {
 		return err
 	}
+	currentDB.Store(conn)
 
-	createTableQuery := `
-	CREATE TABLE IF NOT EXISTS tasks (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT NOT NULL,
-		description TEXT,
-		status TEXT DEFAULT 'pending',
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
+	if err := migrate(db()); err != nil {
+		return err
+	}
+	if err := setupFullTextSearch(); err != nil {
+		return err
+	}
 
-	_, err = db.Exec(createTableQuery)
-	if err != nil {
+	if config().Database.Seed {
+		return seedSampleData()
+	}
+	return nil
+}
+
+// seedSampleData inserts a few example tasks into an empty database so a
+// development instance has something to show. Existing data is left alone.
+func seedSampleData() error {
+	var count int
+	if err := db().QueryRow("SELECT COUNT(*) FROM tasks").Scan(&count); err != nil {
 		return err
 	}
+	if count > 0 {
+		return nil
+	}
 
 	insertSampleData := `
-	INSERT OR IGNORE INTO tasks (title, description, status) VALUES 
-		('Setup Development Environment', 'Install and configure development tools', 'completed'),
-		('Create API Documentation', 'Document all API endpoints and responses', 'in_progress'),
-		('Deploy to Production', 'Deploy application to production environment', 'pending');`
+	INSERT INTO tasks (title, description, status, updated_at, completed_at) VALUES 
+		('Setup Development Environment', 'Install and configure development tools', 'completed', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+		('Create API Documentation', 'Document all API endpoints and responses', 'in_progress', CURRENT_TIMESTAMP, NULL),
+		('Deploy to Production', 'Deploy application to production environment', 'pending', CURRENT_TIMESTAMP, NULL);`
 
-	_, err = db.Exec(insertSampleData)
+	_, err := db().Exec(insertSampleData)
 	return err
 }
 
+// completedAtAssignment is the SET clause that keeps completed_at in step
+// with a status change. It takes the incoming status as its parameter and
+// compares it with the stored one, which the right-hand side of an UPDATE
+// still sees: moving to completed stamps the time, staying completed keeps the
+// original time, and moving anywhere else clears it.
+const completedAtAssignment = "completed_at = CASE WHEN ? != 'completed' THEN NULL WHEN status = 'completed' THEN completed_at ELSE CURRENT_TIMESTAMP END"
+
+// notDeletedPredicate hides soft-deleted tasks; every normal read includes it.
+const notDeletedPredicate = "deleted_at IS NULL"
+
+// activeTaskPredicate selects tasks that are still on the board. The partial
+// index idx_tasks_active is built over it together with notDeletedPredicate,
+// and SQLite only considers that index when a query repeats both terms
+// exactly, so hot-path queries must append them verbatim rather than an
+// equivalent condition or a bound parameter.
+const activeTaskPredicate = "status != 'completed'"
+
+// taskColumns lists the columns read by scanTask, in order.
+const taskColumns = "id, title, description, status, created_at, updated_at, due_date, priority, created_by, updated_by, owner_id, version, parent_id, recurrence, completed_at, archived, position, assignee"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTask reads a row of taskColumns, followed by any extra columns the
+// query selected into extra.
+func scanTask(row rowScanner, extra ...interface{}) (Task, error) {
+	var task Task
+	var completedAt sql.NullTime
+	dest := []interface{}{&task.ID, &task.Title, &task.Description, &task.Status, &task.CreatedAt.Time, &task.UpdatedAt.Time, &task.DueDate, &task.Priority, &task.CreatedBy, &task.UpdatedBy, &task.OwnerID, &task.Version, &task.ParentID, &task.Recurrence, &completedAt, &task.Archived, &task.Position, &task.Assignee}
+	err := row.Scan(append(dest, extra...)...)
+	setScannedTimes(&task, completedAt)
+	return task, err
+}
+
+// dbtx is satisfied by both *sql.DB and *sql.Tx.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// insertTask stores a validated task on behalf of user and fills in the
+// generated id and timestamps. Client-supplied created_at is never trusted.
+func insertTask(ctx context.Context, q dbtx, task *Task, user string) error {
+	task.CreatedBy = nullableString(user)
+	task.UpdatedBy = task.CreatedBy
+	task.OwnerID = task.CreatedBy
+
+	// RETURNING works on both SQLite and PostgreSQL, unlike LastInsertId, and
+	// reads the generated columns back from the row just inserted.
+	// CURRENT_TIMESTAMP is fixed for the statement, so both timestamps match.
+	var completedAt sql.NullTime
+	err := q.QueryRowContext(ctx, "INSERT INTO tasks (title, description, status, due_date, priority, created_by, updated_by, owner_id, assignee, parent_id, recurrence, completed_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CASE WHEN ? = 'completed' THEN CURRENT_TIMESTAMP END, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) RETURNING id, created_at, updated_at, version, completed_at",
+		task.Title, task.Description, task.Status, task.DueDate, task.Priority, task.CreatedBy, task.UpdatedBy, task.OwnerID, task.Assignee, task.ParentID, task.Recurrence, task.Status).Scan(&task.ID, &task.CreatedAt.Time, &task.UpdatedAt.Time, &task.Version, &completedAt)
+	if err != nil {
+		return err
+	}
+	setScannedTimes(task, completedAt)
+
+	if len(task.Tags) > 0 {
+		return setTaskTags(ctx, q, task.ID, task.Tags)
+	}
+	return nil
+}
+
+// nullableString maps an empty string to a nil pointer so it is stored as NULL.
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
 func maskPassword(password string) string {
 	if password == "" {
 		return "not set"
@@ -107,11 +401,49 @@ func maskPassword(password string) string {
 	return "***"
 }
 
+// corsMiddleware applies security.cors_origins. An allowed Origin is echoed
+// back with credentials permitted; a "*" entry switches to wildcard mode,
+// which browsers never combine with credentials. Nothing is added when CORS is
+// disabled or the origin isn't listed.
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
+		origin := c.GetHeader("Origin")
+		cfg := config()
+		if !cfg.Security.CorsEnabled || origin == "" {
+			c.Next()
+			return
+		}
+
+		wildcard := false
+		allowed := false
+		for _, o := range cfg.Security.CorsOrigins {
+			if o == "*" {
+				wildcard = true
+			} else if strings.EqualFold(o, origin) {
+				allowed = true
+			}
+		}
+
+		switch {
+		case wildcard:
+			c.Header("Access-Control-Allow-Origin", "*")
+		case allowed:
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Credentials", "true")
+			c.Writer.Header().Add("Vary", "Origin")
+		default:
+			c.Writer.Header().Add("Vary", "Origin")
+			if c.Request.Method == "OPTIONS" {
+				respondError(c, http.StatusForbidden, errCodeForbidden, "origin not allowed")
+				return
+			}
+			c.Next()
+			return
+		}
+
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Request-ID, If-Match, If-None-Match")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID, ETag, Link, X-Total-Count, X-Page-Limit, X-Page-Offset")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -122,32 +454,531 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// validPriorities is the set of accepted task priorities.
+var validPriorities = map[string]bool{
+	"low":    true,
+	"medium": true,
+	"high":   true,
+}
+
+// priorityRank orders priorities by urgency so sorting ascending puts high
+// priority first, rather than comparing the names alphabetically.
+const priorityRank = "CASE priority WHEN 'high' THEN 0 WHEN 'medium' THEN 1 ELSE 2 END"
+
+// taskSortColumns whitelists the columns accepted by the sort query parameter
+// so the raw value is never interpolated into SQL.
+var taskSortColumns = map[string]string{
+	"id":         "id",
+	"title":      "title",
+	"status":     "status",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"priority":   priorityRank,
+	"position":   "position",
+}
+
+// taskOrderClause turns a sort parameter such as "title" or "-created_at" into
+// an ORDER BY clause, falling back to newest first for missing or unknown
+// values. sort=position orders by the position expression, which is
+// taskPositionColumn for the request.
+func taskOrderClause(sort, position string) string {
+	desc := strings.HasPrefix(sort, "-")
+	column, ok := taskSortColumns[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return "id DESC"
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	switch column {
+	case "id":
+		return "id " + direction
+	case "position":
+		// Tasks that were never placed go last in either direction, oldest
+		// first, the way new cards land at the bottom of a board.
+		return position + " IS NULL, " + position + " " + direction + ", id ASC"
+	}
+	return column + " " + direction + ", id DESC"
+}
+
+// validStatuses is the set of accepted task statuses, in canonical form.
+var validStatuses = map[string]bool{
+	"pending":     true,
+	"in_progress": true,
+	"completed":   true,
+}
+
+// normalizeStatus maps user input such as "In Progress" or "COMPLETED" to the
+// canonical lower_snake_case form that is stored and always returned.
+func normalizeStatus(status string) string {
+	status = strings.ToLower(strings.TrimSpace(status))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(status)
+}
+
+// ownerScope restricts a query to tasks owned by the authenticated user. It is
+// empty for anonymous requests, which see every task as before. Tasks owned by
+// someone else are reported as not found rather than forbidden so their
+// existence isn't revealed.
+func ownerScope(c *gin.Context) (string, []interface{}) {
+	user := currentUser(c)
+	if user == "" {
+		return "", nil
+	}
+	return " AND owner_id = ?", []interface{}{user}
+}
+
+// likeEscaper escapes LIKE wildcards so user input matches literally; queries
+// using it must declare ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// taskListWhere builds the WHERE clause shared by the task listing endpoints
+// from the request's query parameters.
+func taskListWhere(c *gin.Context) (string, []interface{}, error) {
+	conditions := []string{notDeletedPredicate}
+	var args []interface{}
+
+	if status := c.Query("status"); status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, normalizeStatus(status))
+	}
+	if c.Query("archived") != "true" {
+		conditions = append(conditions, "archived = ?")
+		args = append(args, false)
+	}
+	if c.Query("active") == "true" {
+		conditions = append(conditions, activeTaskPredicate)
+	}
+	if c.Query("overdue") == "true" {
+		conditions = append(conditions, "due_date IS NOT NULL", activeTaskPredicate, timestampCompare("due_date", "<"))
+		args = append(args, time.Now().UTC().Format(time.RFC3339))
+	}
+	if priority := c.Query("priority"); priority != "" {
+		conditions = append(conditions, "priority = ?")
+		args = append(args, strings.ToLower(strings.TrimSpace(priority)))
+	}
+	if createdBy := c.Query("created_by"); createdBy != "" {
+		conditions = append(conditions, "created_by = ?")
+		args = append(args, createdBy)
+	}
+	if modifiedBy := c.Query("modified_by"); modifiedBy != "" {
+		conditions = append(conditions, "updated_by = ?")
+		args = append(args, modifiedBy)
+	}
+	if assignee := strings.TrimSpace(c.Query("assignee")); assignee != "" {
+		conditions = append(conditions, "assignee = ?")
+		args = append(args, assignee)
+	}
+	if c.Query("unassigned") == "true" {
+		conditions = append(conditions, "assignee IS NULL")
+	}
+	if tag := strings.TrimSpace(c.Query("tag")); tag != "" {
+		conditions = append(conditions, "id IN (SELECT tt.task_id FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE t.name = ?)")
+		args = append(args, tag)
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		// Both sides are lowered explicitly: SQLite's LIKE already ignores
+		// ASCII case but PostgreSQL's doesn't. SQLite's LOWER only folds
+		// ASCII, so non-ASCII matching stays case-sensitive there.
+		conditions = append(conditions, `(LOWER(title) LIKE ? ESCAPE '\' OR LOWER(COALESCE(description, '')) LIKE ? ESCAPE '\')`)
+		pattern := "%" + escapeLike(strings.ToLower(q)) + "%"
+		args = append(args, pattern, pattern)
+	}
+	for _, bound := range []struct{ param, op string }{
+		{"created_after", ">="},
+		{"created_before", "<="},
+	} {
+		raw := c.Query(bound.param)
+		if raw == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, strings.TrimSpace(raw))
+		if err != nil {
+			return "", nil, newValidationError(msgTimestampInvalid, bound.param)
+		}
+		conditions = append(conditions, timestampCompare("created_at", bound.op))
+		args = append(args, at.UTC().Format(time.RFC3339))
+	}
+
+	scope, scopeArgs := ownerScope(c)
+	return " WHERE " + strings.Join(conditions, " AND ") + scope, append(args, scopeArgs...), nil
+}
+
+// timestampCompare compares a timestamp column against an RFC3339 argument.
+// SQLite stores timestamps as text in more than one layout (CURRENT_TIMESTAMP
+// versus imported RFC3339), so both sides go through datetime() there.
+func timestampCompare(column, op string) string {
+	if isPostgres() {
+		return column + " " + op + " ?"
+	}
+	return "datetime(" + column + ") " + op + " datetime(?)"
+}
+
 func getTasks(c *gin.Context) {
-	rows, err := db.Query("SELECT id, title, description, status, created_at FROM tasks ORDER BY id DESC")
+	ctx := c.Request.Context()
+	where, args, err := taskListWhere(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+	fields, err := requestedFields(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+	position, scoped := taskPositionColumn(c)
+	order, page := taskOrderClause(c.Query("sort"), position), ""
+	cursor, limit, paged, err := cursorPage(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+	if paged {
+		if cursor > 0 {
+			where += " AND id < ?"
+			args = append(args, cursor)
+		}
+		// One extra row tells us whether there is a next page.
+		order, page = "id DESC", " LIMIT ?"
+		args = append(args, limit+1)
+	} else {
+		limit, offset, ok, err := offsetPage(c)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+			return
+		}
+		if ok {
+			var total int
+			if err := db().QueryRowContext(ctx, "SELECT COUNT(*) FROM tasks"+where, args...).Scan(&total); err != nil {
+				respondInternalError(c, err)
+				return
+			}
+			setPageHeaders(c, total, limit, offset)
+			page = " LIMIT ? OFFSET ?"
+			args = append(args, limit, offset)
+		}
+	}
+
+	// A list of one status column shows the positions within that column.
+	columns := taskColumns
+	if scoped {
+		columns += ", " + position
+	}
+	query := "SELECT " + columns + " FROM tasks" + where + " ORDER BY " + order + page
+	rows, err := db().QueryContext(ctx, query, args...)
+	if err != nil {
+		respondInternalError(c, err)
 		return
 	}
 	defer rows.Close()
 
 	var tasks []Task
 	for rows.Next() {
-		var task Task
-		err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.Status, &task.CreatedAt)
+		var scopedPosition sql.NullInt64
+		var extra []interface{}
+		if scoped {
+			extra = append(extra, &scopedPosition)
+		}
+		task, err := scanTask(rows, extra...)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondInternalError(c, err)
 			return
 		}
+		if scoped {
+			task.Position = nil
+			if scopedPosition.Valid {
+				p := int(scopedPosition.Int64)
+				task.Position = &p
+			}
+		}
 		tasks = append(tasks, task)
 	}
 
-	c.JSON(http.StatusOK, tasks)
+	if paged && len(tasks) > limit {
+		tasks = tasks[:limit]
+		c.Header("Link", nextPageLink(c.Request.URL, tasks[limit-1].ID))
+	}
+	if err := attachTags(ctx, db(), tasks); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	body, contentType, err := encodeTasks(c, tasks, fields)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	// Polling clients send back the collection ETag; any change to a task in
+	// the filtered set changes the body and therefore the tag.
+	etag := weakETag(body)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// countTasks returns how many tasks match the same filters as getTasks.
+func countTasks(c *gin.Context) {
+	ctx := c.Request.Context()
+	where, args, err := taskListWhere(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+
+	var count int
+	if err := db().QueryRowContext(ctx, "SELECT COUNT(*) FROM tasks"+where, args...).Scan(&count); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+// getTaskStats returns task counts per status plus a total. Every known status
+// is present, with 0 when no task has it.
+func getTaskStats(c *gin.Context) {
+	ctx := c.Request.Context()
+	scope, args := ownerScope(c)
+	rows, err := db().QueryContext(ctx, "SELECT status, COUNT(*) FROM tasks WHERE "+notDeletedPredicate+scope+" GROUP BY status", args...)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer rows.Close()
+
+	stats := map[string]int{"total": 0}
+	for status := range validStatuses {
+		stats[status] = 0
+	}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		stats[status] += count
+		stats["total"] += count
+	}
+	if err := rows.Err(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// unblockedPredicate excludes tasks that still depend on an unfinished task.
+const unblockedPredicate = "NOT EXISTS (SELECT 1 FROM task_dependencies d JOIN tasks b ON b.id = d.depends_on_id WHERE d.task_id = tasks.id AND b.status != 'completed' AND b.deleted_at IS NULL)"
+
+// nextTaskQuery restricts a taskListWhere filter to active, unblocked tasks
+// and orders them by what should be worked on first.
+func nextTaskQuery(where string) string {
+	where += " AND " + activeTaskPredicate + " AND " + unblockedPredicate
+	return "SELECT " + taskColumns + " FROM tasks" + where + " ORDER BY " + priorityRank + ", due_date IS NULL, due_date ASC, id ASC LIMIT 1"
+}
+
+// getNextTask returns the single task to work on next: the highest-priority
+// non-completed task matching the list filters, then earliest due, then oldest.
+func getNextTask(c *gin.Context) {
+	ctx := c.Request.Context()
+	where, args, err := taskListWhere(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+
+	task, err := scanTask(db().QueryRowContext(ctx, nextTaskQuery(where), args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, errCodeNotFound, "No task to work on next")
+		} else {
+			respondInternalError(c, err)
+		}
+		return
+	}
+
+	taskInRequestZone(c, &task)
+	respondRead(c, http.StatusOK, task)
+}
+
+const defaultDueSoonWindow = 24 * time.Hour
+
+// getDueSoonTasks lists the non-completed tasks matching the list filters
+// that are due between now and now plus ?within= (a Go duration such as 90m
+// or 48h, default 24h), soonest first.
+func getDueSoonTasks(c *gin.Context) {
+	ctx := c.Request.Context()
+	within := defaultDueSoonWindow
+	if raw := c.Query("within"); raw != "" {
+		parsed, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil || parsed <= 0 {
+			respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, newValidationError(msgWithinInvalid)))
+			return
+		}
+		within = parsed
+	}
+	where, args, err := taskListWhere(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+
+	now := time.Now().UTC()
+	where += " AND due_date IS NOT NULL AND " + activeTaskPredicate + " AND " + timestampCompare("due_date", ">=") + " AND " + timestampCompare("due_date", "<=")
+	args = append(args, now.Format(time.RFC3339), now.Add(within).Format(time.RFC3339))
+	tasks, err := queryTasks(ctx, db(), "SELECT "+taskColumns+" FROM tasks"+where+" ORDER BY due_date ASC, id ASC", args...)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	if err := attachTags(ctx, db(), tasks); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	if tasks == nil {
+		tasks = []Task{}
+	}
+
+	respondTasks(c, http.StatusOK, tasks)
+}
+
+const (
+	defaultMaxTitleLength       = 255
+	defaultMaxDescriptionLength = 10000
+)
+
+func maxTitleLength() int {
+	if config().App.MaxTitleLength > 0 {
+		return config().App.MaxTitleLength
+	}
+	return defaultMaxTitleLength
+}
+
+func maxDescriptionLength() int {
+	if config().App.MaxDescriptionLength > 0 {
+		return config().App.MaxDescriptionLength
+	}
+	return defaultMaxDescriptionLength
+}
+
+// validateTask normalizes user-supplied fields in place and reports the first
+// invalid one.
+func validateTask(task *Task) error {
+	task.Title = strings.TrimSpace(task.Title)
+	task.Status = normalizeStatus(task.Status)
+	if task.Title == "" {
+		return newValidationError(msgTitleRequired)
+	}
+	if limit := maxTitleLength(); utf8.RuneCountInString(task.Title) > limit {
+		return newValidationError(msgTitleTooLong, limit)
+	}
+	if limit := maxDescriptionLength(); utf8.RuneCountInString(task.Description) > limit {
+		return newValidationError(msgDescriptionTooLong, limit)
+	}
+	if task.DueDate != nil {
+		due, err := time.Parse(time.RFC3339, strings.TrimSpace(*task.DueDate))
+		if err != nil {
+			return newValidationError(msgDueDateInvalid)
+		}
+		normalized := due.UTC().Format(time.RFC3339)
+		task.DueDate = &normalized
+	}
+	task.Priority = strings.ToLower(strings.TrimSpace(task.Priority))
+	if task.Priority == "" {
+		task.Priority = "medium"
+	}
+	if !validPriorities[task.Priority] {
+		return newValidationError(msgPriorityInvalid)
+	}
+	if task.Assignee != nil {
+		assignee := strings.TrimSpace(*task.Assignee)
+		if assignee == "" {
+			return newValidationError(msgAssigneeBlank)
+		}
+		task.Assignee = &assignee
+	}
+	if task.Status != "" && !validStatuses[task.Status] {
+		return newValidationError(msgStatusInvalid)
+	}
+	task.Recurrence = strings.ToLower(strings.TrimSpace(task.Recurrence))
+	if task.Recurrence == "" {
+		task.Recurrence = recurrenceNone
+	}
+	if _, ok := recurrenceSteps[task.Recurrence]; !ok && task.Recurrence != recurrenceNone {
+		return newValidationError(msgRecurrenceInvalid)
+	}
+	tags, err := normalizeTags(task.Tags)
+	if err != nil {
+		return err
+	}
+	task.Tags = tags
+	return nil
+}
+
+var titleSuffixPattern = regexp.MustCompile(`^(.*) \((\d+)\)$`)
+
+// nextAvailableTitle returns title unchanged when no task uses it yet, otherwise
+// the base title with the next free "(n)" suffix, like a file manager copy.
+func nextAvailableTitle(ctx context.Context, q dbtx, title string) (string, error) {
+	base := title
+	if m := titleSuffixPattern.FindStringSubmatch(title); m != nil {
+		base = m[1]
+	}
+
+	escaped := escapeLike(base)
+	rows, err := q.QueryContext(ctx, `SELECT title FROM tasks WHERE (title = ? OR title = ? OR title LIKE ? ESCAPE '\') AND `+notDeletedPredicate, title, base, escaped+" (%)")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	taken := false
+	highest := 1
+	for rows.Next() {
+		var existing string
+		if err := rows.Scan(&existing); err != nil {
+			return "", err
+		}
+		if existing == title {
+			taken = true
+		}
+		if m := titleSuffixPattern.FindStringSubmatch(existing); m != nil && m[1] == base {
+			if n, err := strconv.Atoi(m[2]); err == nil && n > highest {
+				highest = n
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	if !taken {
+		return title, nil
+	}
+	return fmt.Sprintf("%s (%d)", base, highest+1), nil
 }
 
 func createTask(c *gin.Context) {
+	ctx := c.Request.Context()
 	var task Task
 	if err := c.ShouldBindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
+		return
+	}
+
+	if err := validateTask(&task); err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
 		return
 	}
 
@@ -155,138 +986,783 @@ func createTask(c *gin.Context) {
 		task.Status = "pending"
 	}
 
-	result, err := db.Exec("INSERT INTO tasks (title, description, status) VALUES (?, ?, ?)", task.Title, task.Description, task.Status)
+	// A retried request with the same Idempotency-Key gets the original
+	// response instead of a duplicate task.
+	key, err := idempotencyKey(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
 		return
 	}
+	var fingerprint string
+	if key != "" {
+		if fingerprint, err = requestFingerprint(task); err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		if replayIdempotent(c, key, fingerprint) {
+			return
+		}
+	}
 
-	id, _ := result.LastInsertId()
-	task.ID = int(id)
+	// The parent check, the title suffix and the insert with its tags run in
+	// one transaction so a failure part way leaves nothing behind.
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer tx.Rollback()
 
-	// Get the created_at timestamp
-	err = db.QueryRow("SELECT created_at FROM tasks WHERE id = ?", task.ID).Scan(&task.CreatedAt)
+	if err := storeNewTask(c, tx, &task); err != nil {
+		respondWriteError(c, err)
+		return
+	}
+	body, err := json.Marshal(task)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondInternalError(c, err)
+		return
+	}
+	if key != "" {
+		stored, err := storeIdempotencyKey(ctx, tx, key, currentUser(c), fingerprint, task.ID, body)
+		if err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		if !stored {
+			tx.Rollback()
+			if !replayIdempotent(c, key, fingerprint) {
+				respondError(c, http.StatusConflict, errCodeConflict, "a request with this Idempotency-Key is in progress")
+			}
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondInternalError(c, err)
 		return
 	}
+	publishTaskEvent(eventTaskCreated, task)
+
+	c.Data(http.StatusCreated, "application/json; charset=utf-8", body)
+}
+
+// storeNewTask checks the parent of a validated task, applies the title
+// suffix and inserts it within tx on behalf of the current user.
+func storeNewTask(c *gin.Context, tx dbtx, task *Task) error {
+	ctx := c.Request.Context()
+	if err := checkParent(c, tx, 0, task.ParentID); err != nil {
+		return err
+	}
+	if config().App.TitleAutoSuffix {
+		title, err := nextAvailableTitle(ctx, tx, task.Title)
+		if err != nil {
+			return err
+		}
+		task.Title = title
+	}
+	return insertTask(ctx, tx, task, currentUser(c))
+}
+
+// whereClause builds a parameterized WHERE clause from the set filter fields.
+func (f TaskFilter) whereClause() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if f.Title != nil {
+		conditions = append(conditions, "title = ?")
+		args = append(args, *f.Title)
+	}
+	if f.Description != nil {
+		conditions = append(conditions, "description = ?")
+		args = append(args, *f.Description)
+	}
+	if f.Status != nil {
+		conditions = append(conditions, "status = ?")
+		args = append(args, normalizeStatus(*f.Status))
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	conditions = append(conditions, notDeletedPredicate)
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// createTaskIfAbsent creates the task only when no existing task matches the
+// filter, otherwise it returns the first match. The lookup and insert share a
+// transaction that first takes a lock on the match key, so concurrent
+// callers with the same filter queue up instead of both creating the task.
+// A caller that can't get the lock gets 409 and may retry.
+func createTaskIfAbsent(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req CreateIfAbsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	where, args := req.Filter.whereClause()
+	if where == "" {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "filter must specify at least one field")
+		return
+	}
+	scope, scopeArgs := ownerScope(c)
+	where += scope
+	args = append(args, scopeArgs...)
+
+	task := req.Task
+	if err := validateTask(&task); err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+	if task.Status == "" {
+		task.Status = "pending"
+	}
+
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockMatchKey(ctx, tx, fmt.Sprint(where, args)); err != nil {
+		respondCreateIfAbsentError(c, err)
+		return
+	}
+	existing, err := scanTask(tx.QueryRowContext(ctx, "SELECT "+taskColumns+" FROM tasks"+where+" ORDER BY id LIMIT 1", args...))
+	if err == nil {
+		// Answer with the same representation as GET /tasks/:id.
+		if existing.BlockedBy, err = blockedBy(ctx, tx, existing.ID); err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		if err := taskTags(ctx, tx, &existing); err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, existing)
+		return
+	}
+	if err != sql.ErrNoRows {
+		respondInternalError(c, err)
+		return
+	}
+	if err := checkParent(c, tx, 0, task.ParentID); err != nil {
+		respondParentError(c, err)
+		return
+	}
+
+	if err := insertTask(ctx, tx, &task, currentUser(c)); err != nil {
+		respondCreateIfAbsentError(c, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondCreateIfAbsentError(c, err)
+		return
+	}
+	publishTaskEvent(eventTaskCreated, task)
 
 	c.JSON(http.StatusCreated, task)
 }
 
+// respondCreateIfAbsentError answers 409 when a concurrent create-if-absent
+// held the lock, and 500 for anything else.
+func respondCreateIfAbsentError(c *gin.Context, err error) {
+	if isLockConflict(err) {
+		respondError(c, http.StatusConflict, errCodeConflict, "a matching task is being created by another request; retry")
+		return
+	}
+	respondInternalError(c, err)
+}
+
 func getTask(c *gin.Context) {
-	id := c.Param("id")
-	var task Task
+	ctx := c.Request.Context()
+	id, ok := taskIDParam(c)
+	if !ok {
+		return
+	}
 
-	err := db.QueryRow("SELECT id, title, description, status, created_at FROM tasks WHERE id = ?", id).Scan(&task.ID, &task.Title, &task.Description, &task.Status, &task.CreatedAt)
+	fields, err := requestedFields(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+
+	task, err := lookupTask(c, db(), id)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			respondError(c, http.StatusNotFound, errCodeNotFound, "Task not found")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondInternalError(c, err)
 		}
 		return
 	}
+	if task.BlockedBy, err = blockedBy(ctx, db(), id); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	if err := taskTags(ctx, db(), &task); err != nil {
+		respondInternalError(c, err)
+		return
+	}
 
-	c.JSON(http.StatusOK, task)
+	body, contentType, err := encodeTask(c, task, fields)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	// The tag covers the exact bytes sent, so it changes with anything in
+	// the representation, including blocked_by and tags.
+	etag := strongETag(body)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// lookupTask loads a live task visible to the caller, returning
+// sql.ErrNoRows when it doesn't exist, is deleted, or belongs to someone else.
+func lookupTask(c *gin.Context, q dbtx, id int) (Task, error) {
+	ctx := c.Request.Context()
+	scope, args := ownerScope(c)
+	return scanTask(q.QueryRowContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id = ? AND "+notDeletedPredicate+scope, append([]interface{}{id}, args...)...))
+}
+
+// cloneTask copies a task's content into a new pending task titled
+// "Copy of ...". History such as status and authorship is not copied.
+func cloneTask(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := taskIDParam(c)
+	if !ok {
+		return
+	}
+
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer tx.Rollback()
+
+	source, err := lookupTask(c, tx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, errCodeNotFound, "Task not found")
+		} else {
+			respondInternalError(c, err)
+		}
+		return
+	}
+
+	if err := taskTags(ctx, tx, &source); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	title := []rune("Copy of " + source.Title)
+	if limit := maxTitleLength(); len(title) > limit {
+		title = title[:limit]
+	}
+	task := Task{
+		Title:       string(title),
+		Description: source.Description,
+		Status:      "pending",
+		DueDate:     source.DueDate,
+		Priority:    source.Priority,
+		Assignee:    source.Assignee,
+		Recurrence:  source.Recurrence,
+		Tags:        source.Tags,
+	}
+
+	if config().App.TitleAutoSuffix {
+		title, err := nextAvailableTitle(ctx, tx, task.Title)
+		if err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		task.Title = title
+	}
+
+	if err := insertTask(ctx, tx, &task, currentUser(c)); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	publishTaskEvent(eventTaskCreated, task)
+
+	c.JSON(http.StatusCreated, task)
 }
 
+// taskIDParam parses the :id path parameter, answering 400 itself when it
+// isn't a positive integer.
+func taskIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, newValidationError(msgInvalidID)))
+		return 0, false
+	}
+	return id, true
+}
+
+// requestedVersion returns the task version the client based its update on,
+// from an If-Match header such as "3" or from the version field in the body.
+func requestedVersion(c *gin.Context, bodyVersion int) (int, error) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" {
+		if bodyVersion > 0 {
+			return bodyVersion, nil
+		}
+		return 0, errVersionRequired
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, errors.New("If-Match must be a task version")
+	}
+	return version, nil
+}
+
+var errVersionRequired = errors.New("If-Match header or version is required")
+
+// updateTask replaces a task's fields. It is an optimistic update: the write
+// only applies if the stored version still matches the one the client read,
+// otherwise the client gets 409 and must re-read before retrying. Status
+// changes must follow statusTransitions; an omitted status is left as is.
 func updateTask(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := taskIDParam(c)
+	if !ok {
+		return
+	}
 	var task Task
 	if err := c.ShouldBindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
-	result, err := db.Exec("UPDATE tasks SET title = ?, description = ?, status = ? WHERE id = ?", task.Title, task.Description, task.Status, id)
+	version, err := requestedVersion(c, task.Version)
+	if err == errVersionRequired {
+		respondError(c, http.StatusPreconditionRequired, errCodePreconditionRequired, err.Error())
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	if err := validateTask(&task); err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
 		return
 	}
 
-	// Get the updated task
-	err = db.QueryRow("SELECT id, title, description, status, created_at FROM tasks WHERE id = ?", id).Scan(&task.ID, &task.Title, &task.Description, &task.Status, &task.CreatedAt)
+	updated, err := replaceTask(c, id, version, task)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondWriteError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, task)
+	c.JSON(http.StatusOK, updated)
 }
 
+// replaceTask writes the fields of a validated task over task id, provided it
+// is still at version, and returns the stored result. A stale version or a
+// status change the workflow forbids is a 409 requestError.
+func replaceTask(c *gin.Context, id, version int, task Task) (Task, error) {
+	// The checks, the update and the read-back share one transaction so the
+	// response shows exactly the row this request wrote.
+	tx, err := db().BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		return Task{}, err
+	}
+	defer tx.Rollback()
+
+	updated, err := writeTaskUpdate(c, tx, id, version, task)
+	if err != nil {
+		return Task{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Task{}, err
+	}
+	publishTaskEvent(eventTaskUpdated, updated)
+	return updated, nil
+}
+
+// writeTaskUpdate is replaceTask within the caller's transaction; publishing
+// the change is left to the caller once it commits.
+func writeTaskUpdate(c *gin.Context, tx dbtx, id, version int, task Task) (Task, error) {
+	ctx := c.Request.Context()
+	if err := checkParent(c, tx, id, task.ParentID); err != nil {
+		return Task{}, err
+	}
+
+	current, err := lookupTask(c, tx, id)
+	if err == sql.ErrNoRows {
+		return Task{}, errTaskNotFound
+	}
+	if err != nil {
+		return Task{}, err
+	}
+	if task.Status == "" {
+		task.Status = current.Status
+	}
+	if !canTransition(current.Status, task.Status) {
+		return Task{}, &requestError{
+			status:  http.StatusConflict,
+			code:    errCodeConflict,
+			message: fmt.Sprintf("cannot change status from %s to %s", current.Status, task.Status),
+			details: gin.H{"from": current.Status, "to": task.Status, "allowed": nextStatuses(current.Status)},
+		}
+	}
+
+	// Requiring the status just checked guards against a concurrent change;
+	// that would also have bumped the version, so it surfaces as a conflict.
+	scope, scopeArgs := ownerScope(c)
+	args := append([]interface{}{task.Title, task.Description, task.Status, task.Status, task.DueDate, task.Priority, task.Assignee, task.ParentID, task.Recurrence, nullableString(currentUser(c)), id, version, current.Status}, scopeArgs...)
+	updated, err := queryTasks(ctx, tx, "UPDATE tasks SET title = ?, description = ?, status = ?, "+completedAtAssignment+", due_date = ?, priority = ?, assignee = ?, parent_id = ?, recurrence = ?, updated_by = ?, updated_at = CURRENT_TIMESTAMP, version = version + 1 WHERE id = ? AND version = ? AND status = ? AND "+notDeletedPredicate+scope+" RETURNING "+taskColumns, args...)
+	if err != nil {
+		return Task{}, err
+	}
+	if len(updated) == 0 {
+		var current int
+		err := tx.QueryRowContext(ctx, "SELECT version FROM tasks WHERE id = ? AND "+notDeletedPredicate+scope, append([]interface{}{id}, scopeArgs...)...).Scan(&current)
+		if err == sql.ErrNoRows {
+			return Task{}, errTaskNotFound
+		}
+		if err != nil {
+			return Task{}, err
+		}
+		return Task{}, &requestError{status: http.StatusConflict, code: errCodeConflict, message: "Task was modified by someone else", details: gin.H{"current_version": current}}
+	}
+
+	tags := task.Tags
+	task = updated[0]
+	if tags != nil {
+		if err := setTaskTags(ctx, tx, id, tags); err != nil {
+			return Task{}, err
+		}
+	}
+	if err := taskTags(ctx, tx, &task); err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// deleteTask soft-deletes a task so it can still be recovered; it disappears
+// from every normal read.
 func deleteTask(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := taskIDParam(c)
+	if !ok {
+		return
+	}
+
+	task, err := softDeleteTask(c, db(), id)
+	if err != nil {
+		respondWriteError(c, err)
+		return
+	}
+	publishTaskEvent(eventTaskDeleted, task)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
+}
 
-	result, err := db.Exec("DELETE FROM tasks WHERE id = ?", id)
+// softDeleteTask marks task id deleted and returns it as it was. Publishing
+// the deletion is left to the caller.
+func softDeleteTask(c *gin.Context, q dbtx, id int) (Task, error) {
+	scope, args := ownerScope(c)
+	task, err := scanTask(q.QueryRowContext(c.Request.Context(), "UPDATE tasks SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND "+notDeletedPredicate+scope+" RETURNING "+taskColumns, append([]interface{}{id}, args...)...))
+	if err == sql.ErrNoRows {
+		return Task{}, errTaskNotFound
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// restoreTask undoes a soft delete. Restoring a task that isn't deleted is a
+// conflict; an unknown id is 404.
+func restoreTask(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := taskIDParam(c)
+	if !ok {
+		return
+	}
+
+	scope, args := ownerScope(c)
+	args = append([]interface{}{id}, args...)
+	result, err := db().ExecContext(ctx, "UPDATE tasks SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL"+scope, args...)
+	if err != nil {
+		respondInternalError(c, err)
 		return
 	}
 
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		var exists int
+		err := db().QueryRowContext(ctx, "SELECT 1 FROM tasks WHERE id = ?"+scope, args...).Scan(&exists)
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, errCodeNotFound, "Task not found")
+		} else if err != nil {
+			respondInternalError(c, err)
+		} else {
+			respondError(c, http.StatusConflict, errCodeConflict, "Task is not deleted")
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
+	task, err := scanTask(db().QueryRowContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id = ?", id))
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	publishTaskEvent(eventTaskUpdated, task)
+
+	c.JSON(http.StatusOK, task)
+}
+
+// healthPingTimeout keeps a hung database from stalling health probes, which
+// usually have short timeouts of their own.
+const healthPingTimeout = 2 * time.Second
+
+func healthResponse(status string) HealthResponse {
+	return HealthResponse{
+		Status:    status,
+		Version:   config().App.Version,
+		Timestamp: strconv.FormatInt(time.Now().Unix(), 10),
+	}
+}
+
+// databaseReachable pings the database, logging the reason when it fails.
+func databaseReachable(c *gin.Context) bool {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthPingTimeout)
+	defer cancel()
+	if err := db().PingContext(ctx); err != nil {
+		requestLogger(c).Warn("database ping failed", "error", err)
+		return false
+	}
+	return true
+}
+
+// respondDatabaseHealth answers a probe that depends on the database: 200
+// with okStatus when it responds, 503 with failStatus when it doesn't.
+func respondDatabaseHealth(c *gin.Context, okStatus, failStatus string) {
+	response := healthResponse(okStatus)
+	response.Database = "up"
+	if !databaseReachable(c) {
+		response.Status, response.Database = failStatus, "down"
+		respondRead(c, http.StatusServiceUnavailable, response)
+		return
+	}
+	respondRead(c, http.StatusOK, response)
 }
 
+// healthCheck is kept for existing probes and reflects database reachability
+// like readiness does.
 func healthCheck(c *gin.Context) {
-	response := HealthResponse{
-		Status:    "healthy",
-		Version:   config.App.Version,
-		Timestamp: fmt.Sprintf("%d", c.Request.Context().Value("timestamp")),
+	respondDatabaseHealth(c, "healthy", "unhealthy")
+}
+
+// livenessCheck only shows the process is serving requests; it never touches
+// the database so an outage doesn't get healthy instances restarted.
+func livenessCheck(c *gin.Context) {
+	respondRead(c, http.StatusOK, healthResponse("alive"))
+}
+
+// readinessCheck reports whether this instance can serve traffic, which
+// requires a reachable database.
+func readinessCheck(c *gin.Context) {
+	respondDatabaseHealth(c, "ready", "unavailable")
+}
+
+func setupRouter() *gin.Engine {
+	binding.EnableDecoderDisallowUnknownFields = config().App.RejectUnknownFields
+
+	r := gin.New()
+	r.Use(requestLoggingMiddleware(), recoveryMiddleware())
+	r.Use(requestIDMiddleware())
+	r.Use(bodyLimitMiddleware())
+	r.Use(compressionMiddleware())
+	r.Use(corsMiddleware())
+	r.Use(metricsMiddleware())
+	r.Use(rateLimitMiddleware())
+	r.Use(requestTimeoutMiddleware())
+
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	api := r.Group("/api/v1")
+	{
+		api.GET("/health", healthCheck)
+		api.GET("/health/live", livenessCheck)
+		api.GET("/health/ready", readinessCheck)
+		api.GET("/version", getVersion)
+		api.POST("/auth/login", login)
+		api.GET("/openapi.json", getOpenAPISpec)
+		api.GET("/docs", getAPIDocs)
+	}
+
+	tasks := api.Group("/tasks", authMiddleware(), trackWrites(), timezoneMiddleware())
+	{
+		tasks.GET("", getTasks)
+		tasks.GET("/count", countTasks)
+		tasks.GET("/next", getNextTask)
+		tasks.GET("/due-soon", getDueSoonTasks)
+		tasks.GET("/trash", listTrash)
+		tasks.GET("/stats", getTaskStats)
+		tasks.GET("/search", searchTasks)
+		tasks.GET("/stream", streamTasks)
+		tasks.GET("/events", streamTaskEvents)
+		tasks.GET("/export.csv", exportTasksCSV)
+		tasks.POST("", createTask)
+		tasks.POST("/batch", createTasksBatch)
+		tasks.POST("/batch-ops", batchTaskOperations)
+		tasks.POST("/bulk-delete", bulkDeleteTasks)
+		tasks.POST("/bulk-status", bulkUpdateStatus)
+		tasks.POST("/create-if-absent", createTaskIfAbsent)
+		tasks.POST("/import", importTask)
+		tasks.POST("/reorder", reorderTasks)
+		tasks.GET("/:id", getTask)
+		tasks.GET("/:id/export", exportTask)
+		tasks.PUT("/:id", updateTask)
+		tasks.DELETE("/trash", purgeTrash)
+		tasks.DELETE("/:id", deleteTask)
+		tasks.DELETE("/:id/purge", purgeTask)
+		tasks.POST("/:id/restore", restoreTask)
+		tasks.POST("/:id/clone", cloneTask)
+		tasks.POST("/:id/archive", archiveTask)
+		tasks.POST("/:id/unarchive", unarchiveTask)
+		tasks.GET("/:id/comments", listComments)
+		tasks.GET("/:id/subtasks", listSubtasks)
+		tasks.POST("/:id/dependencies", addDependency)
+		tasks.DELETE("/:id/dependencies/:depends_on", removeDependency)
+		tasks.POST("/:id/comments", createComment)
+	}
+
+	api.POST("/graphql", authMiddleware(), trackWrites(), serveGraphQL)
+	api.GET("/graphql", nonProductionOnly(), getGraphQLPlayground)
+
+	admin := api.Group("/admin", nonProductionOnly(), authMiddleware(), adminOnly(), trackWrites())
+	{
+		admin.POST("/generate", generateTasks)
+	}
+
+	maintenance := api.Group("/admin/maintenance", nonProductionOnly(), authMiddleware())
+	{
+		maintenance.POST("/optimize", runOptimize)
+		maintenance.POST("/vacuum", runVacuum)
+	}
+
+	debug := api.Group("/debug", nonProductionOnly(), authMiddleware())
+	{
+		debug.GET("/schema", getSchemaReport)
 	}
-	c.JSON(http.StatusOK, response)
+
+	return r
 }
 
+const defaultShutdownTimeoutSeconds = 15
+
 func main() {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
 		configPath = "./config.yaml"
 	}
 
-	if err := loadConfig(configPath); err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+	cfg, err := readConfig(configPath)
+	if err != nil {
+		fatal("failed to load config", "error", err)
+	}
+	if err := applyEnvOverrides(&cfg, os.LookupEnv); err != nil {
+		fatal("invalid config override", "error", err)
+	}
+	if err := validateConfig(cfg); err != nil {
+		fatal("invalid config", "path", configPath, "error", err)
+	}
+	setConfig(cfg)
+
+	if err := setupLogging(); err != nil {
+		fatal("invalid logging config", "error", err)
 	}
 
 	if err := initDatabase(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		fatal("failed to initialize database", "error", err)
 	}
-	defer db.Close()
 
-	if config.App.Environment == "production" {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	startMaintenance(ctx)
+	startWebhooks(ctx)
+	startRecurrence(ctx)
+	startTrashPurge(ctx)
+	startConfigReload(ctx, configPath)
+
+	if config().App.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	r := gin.Default()
-	r.Use(corsMiddleware())
+	r := setupRouter()
 
-	api := r.Group("/api/v1")
-	{
-		api.GET("/health", healthCheck)
-		api.GET("/tasks", getTasks)
-		api.POST("/tasks", createTask)
-		api.GET("/tasks/:id", getTask)
-		api.PUT("/tasks/:id", updateTask)
-		api.DELETE("/tasks/:id", deleteTask)
+	server := &http.Server{
+		Addr:    listenAddress(),
+		Handler: r,
 	}
 
-	port := config.App.Port
-	if envPort := os.Getenv("PORT"); envPort != "" {
-		if p, err := strconv.Atoi(envPort); err == nil {
-			port = p
+	logger.Info("starting server", "name", config().App.Name, "version", config().App.Version, "addr", server.Addr, "tls", config().Security.TLS.enabled())
+	err = serve(ctx, server, shutdownTimeout())
+	flushWebhooks()
+	if closeErr := db().Close(); closeErr != nil {
+		logger.Error("failed to close database", "error", closeErr)
+	}
+	if err != nil {
+		fatal("server failed", "error", err)
+	}
+	logger.Info("server stopped")
+}
+
+func shutdownTimeout() time.Duration {
+	if config().App.ShutdownTimeoutSeconds > 0 {
+		return time.Duration(config().App.ShutdownTimeoutSeconds) * time.Second
+	}
+	return defaultShutdownTimeoutSeconds * time.Second
+}
+
+// listenAddress joins app.host and app.port; IPv6 hosts are bracketed.
+func listenAddress() string {
+	return net.JoinHostPort(config().App.Host, strconv.Itoa(config().App.Port))
+}
+
+// serve runs server, over HTTPS when security.tls is set, until ctx is
+// cancelled, then gives in-flight requests up to timeout to finish before
+// returning.
+func serve(ctx context.Context, server *http.Server, timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		if files := config().Security.TLS; files.enabled() {
+			errCh <- server.ListenAndServeTLS(files.CertFile, files.KeyFile)
+		} else {
+			errCh <- server.ListenAndServe()
 		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
 	}
 
-	log.Printf("Starting %s v%s on port %d", config.App.Name, config.App.Version, port)
-	log.Fatal(r.Run(fmt.Sprintf(":%d", port)))
-}
\ No newline at end of file
+	logger.Info("shutting down, waiting for in-flight requests", "timeout", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -2,12 +2,22 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -16,54 +26,24 @@ import (
 func setupTestRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 
-	// Create a temporary config for testing
-	config = Config{
-		App: struct {
-			Name        string `yaml:"name"`
-			Version     string `yaml:"version"`
-			Port        int    `yaml:"port"`
-			Environment string `yaml:"environment"`
-		}{
-			Name:        "test-app",
-			Version:     "1.0.0",
-			Port:        8080,
-			Environment: "test",
-		},
-		Database: struct {
-			Type           string `yaml:"type"`
-			Path           string `yaml:"path"`
-			MaxConnections int    `yaml:"max_connections"`
-			Timeout        int    `yaml:"timeout"`
-		}{
-			Type: "sqlite",
-			Path: ":memory:",
-		},
-		Security: struct {
-			CorsEnabled bool     `yaml:"cors_enabled"`
-			CorsOrigins []string `yaml:"cors_origins"`
-		}{
-			CorsEnabled: true,
-			CorsOrigins: []string{"*"},
-		},
-	}
+	// Create a temporary config for testing. Tests adjust it through
+	// config() before sending requests.
+	cfg := Config{}
+	cfg.App.Name = "test-app"
+	cfg.App.Version = "1.0.0"
+	cfg.App.Port = 8080
+	cfg.App.Environment = "test"
+	cfg.Database.Type = "sqlite"
+	cfg.Database.Path = ":memory:"
+	cfg.Database.Seed = true
+	cfg.Security.CorsEnabled = true
+	cfg.Security.CorsOrigins = []string{"*"}
+	setConfig(cfg)
 
 	// Initialize test database
 	initDatabase()
 
-	r := gin.Default()
-	r.Use(corsMiddleware())
-
-	api := r.Group("/api/v1")
-	{
-		api.GET("/health", healthCheck)
-		api.GET("/tasks", getTasks)
-		api.POST("/tasks", createTask)
-		api.GET("/tasks/:id", getTask)
-		api.PUT("/tasks/:id", updateTask)
-		api.DELETE("/tasks/:id", deleteTask)
-	}
-
-	return r
+	return setupRouter()
 }
 
 func TestHealthCheck(t *testing.T) {
@@ -81,6 +61,63 @@ func TestHealthCheck(t *testing.T) {
 	assert.Equal(t, "healthy", response.Status)
 }
 
+func TestVersion(t *testing.T) {
+	router := setupTestRouter()
+
+	previous := gitCommit
+	gitCommit = "abc123"
+	t.Cleanup(func() { gitCommit = previous })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/version", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	var response VersionResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "test-app", response.Name)
+	assert.Equal(t, "1.0.0", response.Version)
+	assert.Equal(t, "test", response.Environment)
+	assert.Equal(t, runtime.Version(), response.GoVersion)
+	assert.Equal(t, "abc123", response.GitCommit)
+	assert.NotEmpty(t, response.BuildTime, "unknown rather than blank when not injected")
+}
+
+func TestHealthLiveAndReady(t *testing.T) {
+	router := setupTestRouter()
+
+	probe := func(path string) (int, HealthResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		var response HealthResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+
+	code, response := probe("/api/v1/health/ready")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "up", response.Database)
+
+	// With the database gone, readiness and the legacy check fail but the
+	// process is still alive.
+	db().Close()
+
+	code, response = probe("/api/v1/health/live")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "alive", response.Status)
+	assert.Empty(t, response.Database)
+
+	code, response = probe("/api/v1/health/ready")
+	assert.Equal(t, 503, code)
+	assert.Equal(t, "down", response.Database)
+
+	code, response = probe("/api/v1/health")
+	assert.Equal(t, 503, code)
+	assert.Equal(t, "unhealthy", response.Status)
+	assert.Equal(t, "down", response.Database)
+}
+
 func TestCreateTask(t *testing.T) {
 	router := setupTestRouter()
 
@@ -107,6 +144,29 @@ func TestCreateTask(t *testing.T) {
 	assert.NotEqual(t, 0, response.ID)
 }
 
+func TestConcurrentCreatesReadBackTheirOwnRow(t *testing.T) {
+	router := setupTestRouter()
+
+	var wg sync.WaitGroup
+	created := make([]Task, 10)
+	for i := range created {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			created[i] = createTestTask(t, router, Task{Title: fmt.Sprintf("Concurrent %d", i), Tags: []string{strconv.Itoa(i)}})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, task := range created {
+		stored := getTestTask(t, router, task.ID)
+		assert.Equal(t, fmt.Sprintf("Concurrent %d", i), task.Title)
+		assert.Equal(t, stored.CreatedAt, task.CreatedAt)
+		assert.Equal(t, stored.Version, task.Version)
+		assert.Equal(t, []string{strconv.Itoa(i)}, stored.Tags)
+	}
+}
+
 func TestCreateTaskMissingTitle(t *testing.T) {
 	router := setupTestRouter()
 
@@ -121,23 +181,522 @@ func TestCreateTaskMissingTitle(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	router.ServeHTTP(w, req)
 
-	// Current implementation accepts tasks without title validation
-	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "title is required")
+}
+
+func TestCreateTaskBlankTitle(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewBufferString(`{"title":"   \t"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+
+	created := createTestTask(t, router, Task{Title: "  Padded title  "})
+	assert.Equal(t, "Padded title", created.Title)
+}
+
+func TestCreateTaskFieldLengthLimits(t *testing.T) {
+	router := setupTestRouter()
+
+	post := func(task Task) *httptest.ResponseRecorder {
+		jsonValue, _ := json.Marshal(task)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(Task{Title: strings.Repeat("a", 256)})
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "title")
+
+	w = post(Task{Title: "ok", Description: strings.Repeat("d", 10001)})
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "description")
+
+	// Multibyte characters count once each
+	w = post(Task{Title: strings.Repeat("é", 255)})
+	assert.Equal(t, 201, w.Code)
+
+	config().App.MaxTitleLength = 5
+	w = post(Task{Title: "too long"})
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestTaskDueDate(t *testing.T) {
+	router := setupTestRouter()
+
+	withoutDue := createTestTask(t, router, Task{Title: "No deadline"})
+	assert.Nil(t, withoutDue.DueDate)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/"+strconv.Itoa(withoutDue.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), `"due_date":null`)
+
+	due := "2030-01-02T15:04:05+02:00"
+	withDue := createTestTask(t, router, Task{Title: "Deadline", DueDate: &due})
+	if assert.NotNil(t, withDue.DueDate) {
+		assert.Equal(t, "2030-01-02T13:04:05Z", *withDue.DueDate)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/v1/tasks/"+strconv.Itoa(withDue.ID), bytes.NewBufferString(`{"title":"Deadline","status":"pending","due_date":"2031-05-06T07:08:09Z","version":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var updated Task
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if assert.NotNil(t, updated.DueDate) {
+		assert.Equal(t, "2031-05-06T07:08:09Z", *updated.DueDate)
+	}
+}
+
+func TestTaskDueDateInvalid(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewBufferString(`{"title":"Bad","due_date":"next tuesday"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "due_date")
+}
+
+func TestUpdateTaskBlankTitle(t *testing.T) {
+	router := setupTestRouter()
+
+	created := createTestTask(t, router, Task{Title: "Keep me"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/v1/tasks/"+strconv.Itoa(created.ID), bytes.NewBufferString(`{"title":" ","status":"pending","version":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestCreateTaskIfAbsent(t *testing.T) {
+	router := setupTestRouter()
+
+	body := `{"filter":{"title":"Weekly report","status":"pending"},"task":{"title":"Weekly report","description":"first"}}`
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/create-if-absent", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 201, w.Code)
+	var created Task
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.NotEqual(t, 0, created.ID)
+	assert.Equal(t, "pending", created.Status)
+
+	// A second call with the same filter returns the existing task
+	body = `{"filter":{"title":"Weekly report","status":"pending"},"task":{"title":"Weekly report","description":"second"}}`
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/tasks/create-if-absent", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	var existing Task
+	json.Unmarshal(w.Body.Bytes(), &existing)
+	assert.Equal(t, created.ID, existing.ID)
+	assert.Equal(t, "first", existing.Description)
+}
+
+func TestCreateTaskIfAbsentMatchIncludesTagsAndBlockers(t *testing.T) {
+	router := setupTestRouter()
+	blocker := createTestTask(t, router, Task{Title: "Blocker"})
+	tagged := createTestTask(t, router, Task{Title: "Tagged report", Tags: []string{"reports", "weekly"}})
+	assert.Equal(t, 201, addTestDependency(t, router, tagged.ID, blocker.ID).Code)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/create-if-absent", bytes.NewBufferString(`{"filter":{"title":"Tagged report"},"task":{"title":"Tagged report"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	var existing Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &existing))
+	assert.Equal(t, tagged.ID, existing.ID)
+	assert.Equal(t, []string{"reports", "weekly"}, existing.Tags)
+	assert.Equal(t, []int{blocker.ID}, existing.BlockedBy)
+}
+
+func TestCreateTaskIfAbsentConcurrent(t *testing.T) {
+	router := setupTestRouter()
+	// A file database, so the request gets its own connection the way it
+	// would in production.
+	config().Database.Path = filepath.Join(t.TempDir(), "concurrent.db")
+	config().Database.Seed = false
+	assert.NoError(t, initDatabase())
+	defer db().Close()
+
+	// Another caller is mid-way through creating the same task.
+	tx, err := db().Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, lockMatchKey(context.Background(), tx, "other"))
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/tasks/create-if-absent", bytes.NewBufferString(`{"filter":{"title":"Nightly backup"},"task":{"title":"Nightly backup"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		done <- w
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, insertTask(context.Background(), tx, &Task{Title: "Nightly backup", Status: "pending"}, ""))
+	assert.NoError(t, tx.Commit())
+
+	w := <-done
+	assert.Equal(t, 200, w.Code, "the request waits and then finds the task: %s", w.Body.String())
+	var stored int
+	assert.NoError(t, db().QueryRow("SELECT COUNT(*) FROM tasks WHERE title = 'Nightly backup'").Scan(&stored))
+	assert.Equal(t, 1, stored)
+}
+
+func TestCreateTaskIfAbsentLockConflict(t *testing.T) {
+	router := setupTestRouter()
+	config().Database.Path = filepath.Join(t.TempDir(), "conflict.db")
+	config().Database.Seed = false
+	// Give up on a held lock at once instead of waiting for it.
+	config().Database.ConnInitStatements = []string{"PRAGMA busy_timeout = 0"}
+	assert.NoError(t, initDatabase())
+	defer db().Close()
+
+	tx, err := db().Begin()
+	assert.NoError(t, err)
+	defer tx.Rollback()
+	assert.NoError(t, lockMatchKey(context.Background(), tx, "other"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/create-if-absent", bytes.NewBufferString(`{"filter":{"title":"Nightly backup"},"task":{"title":"Nightly backup"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 409, w.Code, w.Body.String())
+}
+
+func TestCreateTaskIfAbsentEmptyFilter(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/create-if-absent", bytes.NewBufferString(`{"filter":{},"task":{"title":"x"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestCreateTaskTitleAutoSuffix(t *testing.T) {
+	router := setupTestRouter()
+	config().App.TitleAutoSuffix = true
+
+	first := createTestTask(t, router, Task{Title: "Deploy"})
+	second := createTestTask(t, router, Task{Title: "Deploy"})
+	third := createTestTask(t, router, Task{Title: "Deploy"})
+	again := createTestTask(t, router, Task{Title: "Deploy (2)"})
+	other := createTestTask(t, router, Task{Title: "Deploy_x"})
+
+	assert.Equal(t, "Deploy", first.Title)
+	assert.Equal(t, "Deploy (2)", second.Title)
+	assert.Equal(t, "Deploy (3)", third.Title)
+	assert.Equal(t, "Deploy (4)", again.Title)
+	assert.Equal(t, "Deploy_x", other.Title)
+}
+
+func TestCreateTaskDuplicateTitleWithoutSuffix(t *testing.T) {
+	router := setupTestRouter()
+
+	first := createTestTask(t, router, Task{Title: "Deploy"})
+	second := createTestTask(t, router, Task{Title: "Deploy"})
+
+	assert.Equal(t, first.Title, second.Title)
+}
+
+func TestGetTasks(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+
+	var tasks []Task
+	err := json.Unmarshal(w.Body.Bytes(), &tasks)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(tasks), 0)
+}
+
+func createTestTask(t *testing.T, router *gin.Engine, task Task) Task {
+	t.Helper()
+
+	jsonValue, _ := json.Marshal(task)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+
+	var created Task
+	json.Unmarshal(w.Body.Bytes(), &created)
+	return created
+}
+
+func listTestTasks(t *testing.T, router *gin.Engine, query string) []Task {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks"+query, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code, w.Body.String())
+
+	var tasks []Task
+	err := json.Unmarshal(w.Body.Bytes(), &tasks)
+	assert.NoError(t, err)
+	return tasks
+}
+
+func TestGetTasksSort(t *testing.T) {
+	router := setupTestRouter()
+
+	createTestTask(t, router, Task{Title: "Alpha", Status: "pending"})
+	createTestTask(t, router, Task{Title: "Zulu", Status: "completed"})
+
+	tasks := listTestTasks(t, router, "?sort=title")
+	assert.True(t, sort.SliceIsSorted(tasks, func(i, j int) bool { return tasks[i].Title < tasks[j].Title }))
+	assert.Equal(t, "Alpha", tasks[0].Title)
+
+	tasks = listTestTasks(t, router, "?sort=-title")
+	assert.True(t, sort.SliceIsSorted(tasks, func(i, j int) bool { return tasks[i].Title > tasks[j].Title }))
+	assert.Equal(t, "Zulu", tasks[0].Title)
+
+	tasks = listTestTasks(t, router, "?sort=id")
+	assert.True(t, sort.SliceIsSorted(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID }))
+
+	tasks = listTestTasks(t, router, "?sort=-created_at")
+	assert.True(t, sort.SliceIsSorted(tasks, func(i, j int) bool { return tasks[i].CreatedAt.After(tasks[j].CreatedAt.Time) }))
+}
+
+func TestGetTasksSortFallback(t *testing.T) {
+	router := setupTestRouter()
+
+	for _, query := range []string{"", "?sort=", "?sort=title;DROP TABLE tasks", "?sort=-password"} {
+		tasks := listTestTasks(t, router, query)
+		assert.True(t, sort.SliceIsSorted(tasks, func(i, j int) bool { return tasks[i].ID > tasks[j].ID }), query)
+	}
+}
+
+func TestGetTasksStatusFilterCaseInsensitive(t *testing.T) {
+	router := setupTestRouter()
+
+	created := createTestTask(t, router, Task{Title: "Mixed case", Status: "In Progress"})
+	assert.Equal(t, "in_progress", created.Status)
+
+	for _, status := range []string{"in_progress", "IN_PROGRESS", "In-Progress", " in progress "} {
+		tasks := listTestTasks(t, router, "?status="+url.QueryEscape(status))
+		assert.NotEmpty(t, tasks, status)
+		for _, task := range tasks {
+			assert.Equal(t, "in_progress", task.Status)
+		}
+	}
+
+	tasks := listTestTasks(t, router, "?status=Completed")
+	assert.NotEmpty(t, tasks)
+	for _, task := range tasks {
+		assert.Equal(t, "completed", task.Status)
+	}
+}
+
+func TestTaskPriority(t *testing.T) {
+	router := setupTestRouter()
+
+	defaulted := createTestTask(t, router, Task{Title: "Default priority"})
+	assert.Equal(t, "medium", defaulted.Priority)
+
+	createTestTask(t, router, Task{Title: "Urgent", Priority: "high"})
+	createTestTask(t, router, Task{Title: "Someday", Priority: "low"})
+
+	tasks := listTestTasks(t, router, "?priority=high")
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, "Urgent", tasks[0].Title)
+
+	rank := map[string]int{"high": 0, "medium": 1, "low": 2}
+	tasks = listTestTasks(t, router, "?sort=priority")
+	assert.Equal(t, "high", tasks[0].Priority)
+	assert.True(t, sort.SliceIsSorted(tasks, func(i, j int) bool { return rank[tasks[i].Priority] < rank[tasks[j].Priority] }))
+
+	tasks = listTestTasks(t, router, "?sort=-priority")
+	assert.Equal(t, "low", tasks[0].Priority)
+}
+
+func TestTaskStatusInvalid(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewBufferString(`{"title":"Bad","status":"blocked"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "status")
+}
+
+func TestTaskPriorityInvalid(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewBufferString(`{"title":"Bad","priority":"critical"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "priority")
+}
+
+func TestGetNextTaskPrefersPriority(t *testing.T) {
+	router := setupTestRouter()
+
+	sooner := "2030-01-01T00:00:00Z"
+	createTestTask(t, router, Task{Title: "Due soon but low", Priority: "low", DueDate: &sooner})
+	urgent := createTestTask(t, router, Task{Title: "Urgent", Priority: "high"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/next", nil)
+	router.ServeHTTP(w, req)
+
+	var next Task
+	json.Unmarshal(w.Body.Bytes(), &next)
+	assert.Equal(t, urgent.ID, next.ID)
+}
+
+func queryPlan(t *testing.T, query string, args ...interface{}) string {
+	t.Helper()
+
+	rows, err := db().Query("EXPLAIN QUERY PLAN "+query, args...)
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		assert.NoError(t, rows.Scan(&id, &parent, &notused, &detail))
+		plan = append(plan, detail)
+	}
+	return strings.Join(plan, "\n")
+}
+
+func TestActiveTaskIndexUsed(t *testing.T) {
+	setupTestRouter()
+
+	assert.Contains(t, queryPlan(t, nextTaskQuery(" WHERE "+notDeletedPredicate)), "idx_tasks_active")
+	assert.Contains(t, queryPlan(t, nextTaskQuery(" WHERE "+notDeletedPredicate+" AND status = ?"), "pending"), "idx_tasks_active")
+	assert.Contains(t, queryPlan(t, "SELECT "+taskColumns+" FROM tasks WHERE "+notDeletedPredicate+" AND "+activeTaskPredicate), "idx_tasks_active")
+}
+
+func TestGetTasksActiveFilter(t *testing.T) {
+	router := setupTestRouter()
+
+	for _, task := range listTestTasks(t, router, "?active=true") {
+		assert.NotEqual(t, "completed", task.Status)
+	}
+}
+
+func getTestTask(t *testing.T, router *gin.Engine, id int) Task {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/"+strconv.Itoa(id), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code, w.Body.String())
+
+	var task Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+	return task
+}
+
+func countTestTasks(t *testing.T, router *gin.Engine, query string) int {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/count"+query, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	var response struct {
+		Count int `json:"count"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response.Count
+}
+
+func TestCountTasks(t *testing.T) {
+	router := setupTestRouter()
+
+	assert.Equal(t, len(listTestTasks(t, router, "")), countTestTasks(t, router, ""))
+	pending := countTestTasks(t, router, "?status=pending")
+
+	created := createTestTask(t, router, Task{Title: "Counted"})
+	assert.Equal(t, pending+1, countTestTasks(t, router, "?status=Pending"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/tasks/"+strconv.Itoa(created.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, pending, countTestTasks(t, router, "?status=pending"))
+}
+
+func TestGetTaskStats(t *testing.T) {
+	router := setupTestRouter()
+
+	_, err := db().Exec("UPDATE tasks SET status = 'pending'")
+	assert.NoError(t, err)
+	createTestTask(t, router, Task{Title: "One more", Status: "pending"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/stats", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	var stats map[string]int
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	total := countTestTasks(t, router, "")
+	assert.Equal(t, map[string]int{"pending": total, "in_progress": 0, "completed": 0, "total": total}, stats)
 }
 
-func TestGetTasks(t *testing.T) {
+func TestGetNextTask(t *testing.T) {
 	router := setupTestRouter()
 
+	later := "2030-06-01T00:00:00Z"
+	sooner := "2030-01-01T00:00:00Z"
+	createTestTask(t, router, Task{Title: "Later", DueDate: &later})
+	soonest := createTestTask(t, router, Task{Title: "Sooner", DueDate: &sooner})
+	createTestTask(t, router, Task{Title: "Done", Status: "completed", DueDate: &sooner})
+
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/api/v1/tasks", nil)
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/next", nil)
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, 200, w.Code)
+	var next Task
+	json.Unmarshal(w.Body.Bytes(), &next)
+	assert.Equal(t, soonest.ID, next.ID)
 
-	var tasks []Task
-	err := json.Unmarshal(w.Body.Bytes(), &tasks)
-	assert.NoError(t, err)
-	assert.GreaterOrEqual(t, len(tasks), 0)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks/next?status=completed", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 404, w.Code)
 }
 
 func TestGetTask(t *testing.T) {
@@ -197,6 +756,7 @@ func TestUpdateTask(t *testing.T) {
 		Title:       "Updated Task",
 		Description: "Updated description",
 		Status:      "completed",
+		Version:     createdTask.Version,
 	}
 	jsonValue, _ = json.Marshal(updatedTask)
 
@@ -248,6 +808,202 @@ func TestDeleteTask(t *testing.T) {
 	assert.Equal(t, 404, w.Code)
 }
 
+func TestDeleteTaskIsSoft(t *testing.T) {
+	router := setupTestRouter()
+
+	created := createTestTask(t, router, Task{Title: "Soft delete me"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/tasks/"+strconv.Itoa(created.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	// The row is still stored but hidden from every read
+	var deletedAt *string
+	err := db().QueryRow("SELECT deleted_at FROM tasks WHERE id = ?", created.ID).Scan(&deletedAt)
+	assert.NoError(t, err)
+	assert.NotNil(t, deletedAt)
+
+	for _, task := range listTestTasks(t, router, "") {
+		assert.NotEqual(t, created.ID, task.ID)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/v1/tasks/"+strconv.Itoa(created.ID), bytes.NewBufferString(`{"title":"Revived","version":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+
+	// Deleting again reports the task as missing
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/tasks/"+strconv.Itoa(created.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/tasks/99999", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestRestoreTask(t *testing.T) {
+	router := setupTestRouter()
+
+	created := createTestTask(t, router, Task{Title: "Undo me"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/tasks/"+strconv.Itoa(created.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/tasks/"+strconv.Itoa(created.ID)+"/restore", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	var restored Task
+	json.Unmarshal(w.Body.Bytes(), &restored)
+	assert.Equal(t, created.ID, restored.ID)
+	assert.Equal(t, "Undo me", restored.Title)
+
+	found := false
+	for _, task := range listTestTasks(t, router, "") {
+		if task.ID == created.ID {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestRestoreTaskNotDeleted(t *testing.T) {
+	router := setupTestRouter()
+
+	created := createTestTask(t, router, Task{Title: "Still here"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/"+strconv.Itoa(created.ID)+"/restore", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 409, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/tasks/99999/restore", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestCloneTask(t *testing.T) {
+	router := setupTestRouter()
+
+	due := "2030-01-02T15:04:05Z"
+	source := createTestTask(t, router, Task{Title: "Weekly report", Description: "Numbers", Priority: "high", DueDate: &due, Status: "completed"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/"+strconv.Itoa(source.ID)+"/clone", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+
+	var clone Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &clone))
+	assert.NotEqual(t, source.ID, clone.ID)
+	assert.Equal(t, "Copy of Weekly report", clone.Title)
+	assert.Equal(t, "Numbers", clone.Description)
+	assert.Equal(t, "high", clone.Priority)
+	assert.Equal(t, "pending", clone.Status)
+	if assert.NotNil(t, clone.DueDate) {
+		assert.Equal(t, due, *clone.DueDate)
+	}
+	assert.Equal(t, clone, getTestTask(t, router, clone.ID))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/tasks/99999/clone", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestGetTasksCreatedAndModifiedBy(t *testing.T) {
+	router := setupTestRouter()
+
+	_, err := db().Exec("INSERT INTO tasks (title, description, status, created_by, updated_by, updated_at) VALUES ('Alice wrote', '', 'pending', 'alice', 'bob', CURRENT_TIMESTAMP), ('Bob wrote', '', 'completed', 'bob', 'bob', CURRENT_TIMESTAMP)")
+	assert.NoError(t, err)
+
+	tasks := listTestTasks(t, router, "?created_by=alice")
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, "Alice wrote", tasks[0].Title)
+
+	tasks = listTestTasks(t, router, "?modified_by=bob")
+	assert.Len(t, tasks, 2)
+
+	tasks = listTestTasks(t, router, "?modified_by=bob&status=completed")
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, "Bob wrote", tasks[0].Title)
+}
+
+func TestTaskAssignee(t *testing.T) {
+	router := setupTestRouter()
+
+	alice := "  alice "
+	assigned := createTestTask(t, router, Task{Title: "Alice's", Assignee: &alice})
+	if assert.NotNil(t, assigned.Assignee) {
+		assert.Equal(t, "alice", *assigned.Assignee)
+	}
+	unassigned := createTestTask(t, router, Task{Title: "Nobody's"})
+	assert.Nil(t, unassigned.Assignee)
+
+	tasks := listTestTasks(t, router, "?assignee=alice")
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, assigned.ID, tasks[0].ID)
+	}
+	for _, task := range listTestTasks(t, router, "?unassigned=true") {
+		assert.Nil(t, task.Assignee)
+		assert.NotEqual(t, assigned.ID, task.ID)
+	}
+
+	// A PUT replaces the assignee; leaving it out unassigns the task.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/v1/tasks/"+strconv.Itoa(assigned.ID), bytes.NewBufferString(`{"title":"Alice's","version":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Nil(t, getTestTask(t, router, assigned.ID).Assignee)
+	assert.Empty(t, listTestTasks(t, router, "?assignee=alice"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/tasks", bytes.NewBufferString(`{"title":"Blank","assignee":"   "}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "assignee must not be blank")
+}
+
+func TestUpdateTaskRecordsUpdatedBy(t *testing.T) {
+	router := setupTestRouter()
+
+	created := createTestTask(t, router, Task{Title: "Anonymous"})
+	assert.Nil(t, created.CreatedBy)
+
+	// Only the owner may edit, so hand the task over to carol first.
+	_, err := db().Exec("UPDATE tasks SET owner_id = 'carol' WHERE id = ?", created.ID)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("PUT", "/api/v1/tasks/"+strconv.Itoa(created.ID), bytes.NewBufferString(`{"title":"Edited","status":"pending","version":1}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(created.ID)}}
+	c.Set(userIDKey, "carol")
+	updateTask(c)
+
+	assert.Equal(t, 200, w.Code)
+	var updated Task
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if assert.NotNil(t, updated.UpdatedBy) {
+		assert.Equal(t, "carol", *updated.UpdatedBy)
+	}
+
+	tasks := listTestTasks(t, router, "?modified_by=carol")
+	assert.Len(t, tasks, 1)
+}
+
 func TestCorsMiddleware(t *testing.T) {
 	router := setupTestRouter()
 
@@ -257,8 +1013,53 @@ func TestCorsMiddleware(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, 204, w.Code)
-	// Current implementation uses wildcard CORS
+	// The test config lists "*", which enables wildcard mode explicitly
 	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCorsAllowlist(t *testing.T) {
+	router := setupTestRouter()
+	config().Security.CorsOrigins = []string{"http://localhost:3000"}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("OPTIONS", "/api/v1/tasks", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 204, w.Code)
+	assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("OPTIONS", "/api/v1/tasks", nil)
+	req.Header.Set("Origin", "http://evil.example")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 403, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks", nil)
+	req.Header.Set("Origin", "http://evil.example")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCorsDisabled(t *testing.T) {
+	router := setupTestRouter()
+	config().Security.CorsEnabled = false
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
 }
 
 func TestMain(m *testing.M) {
@@ -277,3 +1078,345 @@ func TestMain(m *testing.M) {
 
 	os.Exit(code)
 }
+
+func TestListenAddress(t *testing.T) {
+	setupTestRouter()
+	assert.Equal(t, ":8080", listenAddress())
+
+	config().App.Host = "127.0.0.1"
+	assert.Equal(t, "127.0.0.1:8080", listenAddress())
+
+	config().App.Host = "::1"
+	assert.Equal(t, "[::1]:8080", listenAddress())
+}
+
+func TestServeShutsDownGracefully(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, server, 5*time.Second) }()
+
+	var resp *http.Response
+	respCh := make(chan error, 1)
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	go func() {
+		var err error
+		resp, err = http.Get("http://" + addr + "/slow")
+		respCh <- err
+	}()
+	<-started
+
+	// Shutdown must wait for the in-flight request to complete.
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	assert.NoError(t, <-respCh)
+	assert.Equal(t, 200, resp.StatusCode)
+	resp.Body.Close()
+	assert.NoError(t, <-done)
+}
+
+func TestSeedSampleData(t *testing.T) {
+	router := setupTestRouter()
+	assert.Equal(t, 3, countTestTasks(t, router, ""))
+
+	// Seeding again must not duplicate the sample rows.
+	assert.NoError(t, seedSampleData())
+	assert.Equal(t, 3, countTestTasks(t, router, ""))
+
+	config().Database.Seed = false
+	assert.NoError(t, initDatabase())
+	assert.Equal(t, 0, countTestTasks(t, router, ""))
+}
+
+func TestUpdatedAtMaintainedOnWrites(t *testing.T) {
+	router := setupTestRouter()
+
+	created := createTestTask(t, router, Task{Title: "Tracked"})
+	assert.NotEmpty(t, created.UpdatedAt)
+	assert.Equal(t, created.CreatedAt, created.UpdatedAt)
+
+	// Timestamps have second resolution, so backdate instead of sleeping.
+	_, err := db().Exec("UPDATE tasks SET updated_at = '2000-01-01 00:00:00'")
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/v1/tasks/1", bytes.NewBufferString(`{"title":"Tracked","status":"completed","version":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	var updated Task
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	assert.NotEqual(t, 2000, updated.UpdatedAt.Year())
+
+	tasks := listTestTasks(t, router, "?sort=-updated_at")
+	assert.Equal(t, 1, tasks[0].ID)
+}
+
+func TestUpdateTaskOptimisticConcurrency(t *testing.T) {
+	router := setupTestRouter()
+
+	created := createTestTask(t, router, Task{Title: "Shared"})
+	assert.Equal(t, 1, created.Version)
+	path := "/api/v1/tasks/" + strconv.Itoa(created.ID)
+
+	put := func(body, ifMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Alice saves first, based on version 1.
+	w := put(`{"title":"Alice's edit"}`, `"1"`)
+	assert.Equal(t, 200, w.Code)
+	var saved Task
+	json.Unmarshal(w.Body.Bytes(), &saved)
+	assert.Equal(t, 2, saved.Version)
+
+	// Bob also read version 1; his write must not clobber Alice's.
+	w = put(`{"title":"Bob's edit","version":1}`, "")
+	assert.Equal(t, 409, w.Code)
+	var conflict APIError
+	json.Unmarshal(w.Body.Bytes(), &conflict)
+	assert.Equal(t, errCodeConflict, conflict.Code)
+	assert.Equal(t, map[string]interface{}{"current_version": float64(2)}, conflict.Details)
+
+	stored := getTestTask(t, router, created.ID)
+	assert.Equal(t, "Alice's edit", stored.Title)
+
+	// Without a version the update is refused outright.
+	assert.Equal(t, 428, put(`{"title":"Blind write"}`, "").Code)
+	assert.Equal(t, 400, put(`{"title":"Bad tag"}`, `"abc"`).Code)
+
+	// Retrying with the current version succeeds.
+	assert.Equal(t, 200, put(`{"title":"Bob's edit"}`, `W/"2"`).Code)
+}
+
+func TestInvalidTaskID(t *testing.T) {
+	router := setupTestRouter()
+
+	for _, id := range []string{"abc", "0", "-1", "1.5", "1abc"} {
+		for _, method := range []string{"GET", "PUT", "DELETE"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, "/api/v1/tasks/"+id, bytes.NewBufferString(`{"title":"x","version":1}`))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, 400, w.Code, method+" "+id)
+			assert.Contains(t, w.Body.String(), "invalid id", method+" "+id)
+		}
+	}
+}
+
+func TestGetTasksTextSearch(t *testing.T) {
+	router := setupTestRouter()
+
+	createTestTask(t, router, Task{Title: "Deploy backend", Description: "Roll out v2"})
+	createTestTask(t, router, Task{Title: "Write docs", Description: "Explain the DEPLOYMENT steps", Status: "in_progress"})
+	createTestTask(t, router, Task{Title: "Fix 100% CPU bug", Description: "", Status: "completed"})
+	createTestTask(t, router, Task{Title: "Unrelated", Description: "Nothing to see"})
+
+	titles := func(tasks []Task) []string {
+		var out []string
+		for _, task := range tasks {
+			out = append(out, task.Title)
+		}
+		return out
+	}
+
+	// "Deploy to Production" comes from the seed data.
+	assert.ElementsMatch(t, []string{"Deploy backend", "Write docs", "Deploy to Production"}, titles(listTestTasks(t, router, "?q=deploy")))
+	assert.ElementsMatch(t, []string{"Deploy backend", "Deploy to Production"}, titles(listTestTasks(t, router, "?q=deploy&status=pending")))
+
+	// LIKE wildcards in the term are matched literally.
+	assert.ElementsMatch(t, []string{"Fix 100% CPU bug"}, titles(listTestTasks(t, router, "?q="+url.QueryEscape("100%"))))
+	assert.Empty(t, listTestTasks(t, router, "?q=_"))
+}
+
+func TestGetTasksCreatedRange(t *testing.T) {
+	router := setupTestRouter()
+
+	old := createTestTask(t, router, Task{Title: "Last year"})
+	recent := createTestTask(t, router, Task{Title: "This week"})
+	_, err := db().Exec("UPDATE tasks SET created_at = '2023-06-01 12:00:00'")
+	assert.NoError(t, err)
+	_, err = db().Exec("UPDATE tasks SET created_at = '2024-03-04T09:30:00Z' WHERE id = ?", recent.ID)
+	assert.NoError(t, err)
+
+	ids := func(tasks []Task) []int {
+		var out []int
+		for _, task := range tasks {
+			out = append(out, task.ID)
+		}
+		return out
+	}
+
+	assert.Equal(t, []int{recent.ID}, ids(listTestTasks(t, router, "?created_after=2024-03-04T00:00:00Z")))
+	assert.NotContains(t, ids(listTestTasks(t, router, "?created_before=2024-01-01T00:00:00Z")), recent.ID)
+	assert.Contains(t, ids(listTestTasks(t, router, "?created_before=2024-01-01T00:00:00Z")), old.ID)
+	// Bounds are inclusive and offsets are converted to UTC.
+	assert.Equal(t, []int{recent.ID}, ids(listTestTasks(t, router, "?created_after="+url.QueryEscape("2024-03-04T10:30:00+01:00")+"&created_before=2024-03-04T09:30:00Z")))
+
+	for _, query := range []string{"?created_after=yesterday", "?created_before=2024-03-04"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/tasks"+query, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code, query)
+		assert.Contains(t, w.Body.String(), "must be an RFC3339 timestamp", query)
+	}
+}
+
+func TestGetTasksOverdue(t *testing.T) {
+	router := setupTestRouter()
+
+	past := time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+
+	late := createTestTask(t, router, Task{Title: "Late", DueDate: &past})
+	createTestTask(t, router, Task{Title: "Late but done", DueDate: &past, Status: "completed"})
+	createTestTask(t, router, Task{Title: "Not yet due", DueDate: &future})
+	createTestTask(t, router, Task{Title: "No due date"})
+
+	tasks := listTestTasks(t, router, "?overdue=true")
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, late.ID, tasks[0].ID)
+	}
+}
+
+func TestGetDueSoonTasks(t *testing.T) {
+	router := setupTestRouter()
+
+	at := func(d time.Duration) *string {
+		s := time.Now().Add(d).UTC().Format(time.RFC3339)
+		return &s
+	}
+	later := createTestTask(t, router, Task{Title: "Tonight", DueDate: at(6 * time.Hour)})
+	sooner := createTestTask(t, router, Task{Title: "Within the hour", DueDate: at(30 * time.Minute)})
+	createTestTask(t, router, Task{Title: "Done already", DueDate: at(time.Hour), Status: "completed"})
+	createTestTask(t, router, Task{Title: "Overdue", DueDate: at(-time.Hour)})
+	nextWeek := createTestTask(t, router, Task{Title: "Next week", DueDate: at(7 * 24 * time.Hour)})
+	createTestTask(t, router, Task{Title: "Whenever"})
+
+	ids := func(tasks []Task) []int {
+		var ids []int
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []int{sooner.ID, later.ID}, ids(listTestTasks(t, router, "/due-soon")))
+	assert.Equal(t, []int{sooner.ID}, ids(listTestTasks(t, router, "/due-soon?within=1h")))
+	assert.Equal(t, []int{sooner.ID, later.ID, nextWeek.ID}, ids(listTestTasks(t, router, "/due-soon?within=200h")))
+
+	for _, within := range []string{"tomorrow", "-1h", "0s"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/tasks/due-soon?within="+within, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code, within)
+		assert.Contains(t, w.Body.String(), errCodeValidation, within)
+	}
+}
+
+func TestCompletedAt(t *testing.T) {
+	router := setupTestRouter()
+	created := createTestTask(t, router, Task{Title: "Ship it"})
+	assert.Nil(t, created.CompletedAt)
+
+	put := func(body string) Task {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/api/v1/tasks/"+strconv.Itoa(created.ID), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		var task Task
+		json.Unmarshal(w.Body.Bytes(), &task)
+		return task
+	}
+
+	task := put(`{"title":"Ship it","status":"completed","version":1}`)
+	assert.NotNil(t, task.CompletedAt, "moving to completed stamps the time")
+
+	_, err := db().Exec("UPDATE tasks SET completed_at = '2020-01-01 00:00:00' WHERE id = ?", created.ID)
+	assert.NoError(t, err)
+	task = put(`{"title":"Shipped","status":"completed","version":2}`)
+	if assert.NotNil(t, task.CompletedAt) {
+		assert.Equal(t, "2020-01-01T00:00:00Z", task.CompletedAt.String(), "staying completed keeps the original time")
+	}
+
+	config().Workflow.ReopenTo = []string{"in_progress"}
+	task = put(`{"title":"Shipped","status":"in_progress","version":3}`)
+	assert.Nil(t, task.CompletedAt, "reopening clears it")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/bulk-status", bytes.NewBufferString(`{"ids":[`+strconv.Itoa(created.ID)+`],"status":"completed"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var stored *string
+	assert.NoError(t, db().QueryRow("SELECT completed_at FROM tasks WHERE id = ?", created.ID).Scan(&stored))
+	assert.NotNil(t, stored, "bulk status changes stamp it too")
+
+	done := createTestTask(t, router, Task{Title: "Already done", Status: "completed"})
+	assert.NotNil(t, done.CompletedAt)
+}
+
+func TestSparseFieldsets(t *testing.T) {
+	router := setupTestRouter()
+	created := createTestTask(t, router, Task{Title: "Sparse", Tags: []string{"mobile"}})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/v1/tasks?fields=id,%20title,status&tag=mobile")
+	assert.Equal(t, 200, w.Code)
+	var list []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, []map[string]interface{}{{"id": float64(created.ID), "title": "Sparse", "status": "pending"}}, list)
+
+	w = get("/api/v1/tasks/" + strconv.Itoa(created.ID) + "?fields=tags")
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"tags":["mobile"]}`, w.Body.String())
+
+	w = get("/api/v1/tasks?fields=id,secret")
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), `unknown field \"secret\"`)
+
+	w = get("/api/v1/tasks?fields=")
+	assert.Equal(t, 400, w.Code)
+
+	// Without the parameter every field is returned.
+	w = get("/api/v1/tasks/" + strconv.Itoa(created.ID))
+	assert.Contains(t, w.Body.String(), `"description"`)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errWritesActive is returned when VACUUM is skipped because requests that
+// modify data are still in flight.
+var errWritesActive = errors.New("writes are in progress")
+
+// writeGate is held shared by every in-flight request that may write to the
+// database, and by the background jobs that write (recurrence, the trash
+// purge and the webhook dispatcher), and exclusively by VACUUM, so new
+// writers wait for VACUUM to finish instead of racing it for the database
+// lock.
+var writeGate sync.RWMutex
+
+// holdWrites takes writeGate shared for a background write, as trackWrites
+// does for requests, and returns the function that releases it.
+func holdWrites() func() {
+	writeGate.RLock()
+	return writeGate.RUnlock
+}
+
+// VacuumResult reports how much space a VACUUM reclaimed.
+type VacuumResult struct {
+	SizeBeforeBytes int64 `json:"size_before_bytes"`
+	SizeAfterBytes  int64 `json:"size_after_bytes"`
+	ReclaimedBytes  int64 `json:"reclaimed_bytes"`
+	DurationMS      int64 `json:"duration_ms"`
+}
+
+// trackWrites holds writeGate for requests with side effects so maintenance
+// never runs VACUUM underneath them.
+func trackWrites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		writeGate.RLock()
+		defer writeGate.RUnlock()
+		c.Next()
+	}
+}
+
+// databaseSize returns the size of the main database file in bytes.
+func databaseSize(ctx context.Context) (int64, error) {
+	if isPostgres() {
+		var size int64
+		err := db().QueryRowContext(ctx, "SELECT pg_database_size(current_database())").Scan(&size)
+		return size, err
+	}
+
+	var pageCount, pageSize int64
+	if err := db().QueryRowContext(ctx, "PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0, err
+	}
+	if err := db().QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return pageCount * pageSize, nil
+}
+
+// optimizeDatabase runs PRAGMA optimize, which refreshes query planner
+// statistics where SQLite thinks they are stale. PostgreSQL gets ANALYZE.
+func optimizeDatabase(ctx context.Context) (time.Duration, error) {
+	statement := "PRAGMA optimize"
+	if isPostgres() {
+		statement = "ANALYZE"
+	}
+
+	start := time.Now()
+	_, err := db().ExecContext(ctx, statement)
+	return time.Since(start), err
+}
+
+// vacuumDatabase rebuilds the database file to reclaim free pages. It refuses
+// to run while writes are active since VACUUM holds an exclusive lock, and
+// holds writes that arrive meanwhile until it is done.
+func vacuumDatabase(ctx context.Context) (VacuumResult, error) {
+	if !writeGate.TryLock() {
+		return VacuumResult{}, errWritesActive
+	}
+	defer writeGate.Unlock()
+
+	before, err := databaseSize(ctx)
+	if err != nil {
+		return VacuumResult{}, err
+	}
+
+	start := time.Now()
+	if _, err := db().ExecContext(ctx, "VACUUM"); err != nil {
+		return VacuumResult{}, err
+	}
+	elapsed := time.Since(start)
+
+	after, err := databaseSize(ctx)
+	if err != nil {
+		return VacuumResult{}, err
+	}
+
+	return VacuumResult{
+		SizeBeforeBytes: before,
+		SizeAfterBytes:  after,
+		ReclaimedBytes:  before - after,
+		DurationMS:      elapsed.Milliseconds(),
+	}, nil
+}
+
+// startMaintenance schedules PRAGMA optimize and VACUUM on the intervals in
+// the database config until ctx is cancelled. A zero interval disables the job.
+func startMaintenance(ctx context.Context) {
+	if minutes := config().Database.OptimizeIntervalMinutes; minutes > 0 {
+		go runEvery(ctx, time.Duration(minutes)*time.Minute, func() {
+			elapsed, err := optimizeDatabase(ctx)
+			if err != nil {
+				logger.Error("PRAGMA optimize failed", "error", err)
+				return
+			}
+			logger.Info("PRAGMA optimize finished", "duration_ms", elapsed.Milliseconds())
+		})
+	}
+
+	if hours := config().Database.VacuumIntervalHours; hours > 0 {
+		go runEvery(ctx, time.Duration(hours)*time.Hour, func() {
+			result, err := vacuumDatabase(ctx)
+			if err != nil {
+				logger.Warn("VACUUM skipped", "error", err)
+				return
+			}
+			logger.Info("VACUUM finished", "duration_ms", result.DurationMS, "reclaimed_bytes", result.ReclaimedBytes)
+		})
+	}
+}
+
+func runEvery(ctx context.Context, interval time.Duration, job func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job()
+		}
+	}
+}
+
+func runOptimize(c *gin.Context) {
+	ctx := c.Request.Context()
+	elapsed, err := optimizeDatabase(ctx)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"duration_ms": elapsed.Milliseconds()})
+}
+
+func runVacuum(c *gin.Context) {
+	ctx := c.Request.Context()
+	result, err := vacuumDatabase(ctx)
+	if err != nil {
+		if errors.Is(err, errWritesActive) {
+			respondError(c, http.StatusConflict, errCodeConflict, "VACUUM skipped: "+err.Error())
+		} else {
+			respondInternalError(c, err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
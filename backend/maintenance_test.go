@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVacuumEndpoint(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/admin/maintenance/vacuum", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	var result VacuumResult
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+	assert.Greater(t, result.SizeBeforeBytes, int64(0))
+	assert.Equal(t, result.SizeBeforeBytes-result.SizeAfterBytes, result.ReclaimedBytes)
+}
+
+func TestVacuumSkippedWhileWritesActive(t *testing.T) {
+	router := setupTestRouter()
+
+	writeGate.RLock()
+	defer writeGate.RUnlock()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/admin/maintenance/vacuum", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 409, w.Code)
+}
+
+func TestWritesWaitForVacuum(t *testing.T) {
+	router := setupTestRouter()
+
+	// Stand in for a VACUUM that is still running.
+	writeGate.Lock()
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/tasks", strings.NewReader(`{"title":"After vacuum"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		done <- w.Code
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("write ran while VACUUM held the database")
+	case <-time.After(50 * time.Millisecond):
+	}
+	writeGate.Unlock()
+	assert.Equal(t, 201, <-done)
+}
+
+func TestBackgroundWritesWaitForVacuum(t *testing.T) {
+	router := setupTestRouter()
+	createTestTask(t, router, Task{Title: "Recurs", Status: "completed", Recurrence: "daily"})
+	webhooks = newWebhookDispatcher([]string{"http://example.invalid"}, "", 10, 1)
+	defer func() { webhooks = nil }()
+
+	jobs := map[string]func(){
+		"recurrence": func() {
+			_, err := generateRecurrences(context.Background(), time.Now())
+			assert.NoError(t, err)
+		},
+		"trash purge": func() {
+			_, err := purgeExpiredTrash(context.Background())
+			assert.NoError(t, err)
+		},
+		"webhook store": func() {
+			publishTaskEvent(eventTaskCreated, Task{ID: 1})
+			webhooks.flush()
+		},
+		"webhook claim": func() {
+			_, _, err := webhooks.claim(context.Background())
+			assert.NoError(t, err)
+		},
+	}
+	for name, job := range jobs {
+		writeGate.Lock()
+		done := make(chan struct{})
+		go func() {
+			job()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Errorf("%s wrote while VACUUM held the database", name)
+		case <-time.After(50 * time.Millisecond):
+		}
+		writeGate.Unlock()
+		<-done
+	}
+}
+
+func TestMaintenanceHiddenInProduction(t *testing.T) {
+	router := setupTestRouter()
+	config().App.Environment = "production"
+
+	for _, path := range []string{"/api/v1/admin/maintenance/vacuum", "/api/v1/admin/maintenance/optimize"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 404, w.Code, path)
+	}
+}
+
+func TestOptimizeEndpoint(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/admin/maintenance/optimize", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultLanguage = "en"
+
+// Message keys for client-facing validation errors. The text for each lives
+// in messageCatalog rather than in the handlers.
+const (
+	msgTitleRequired         = "title_required"
+	msgTitleTooLong          = "title_too_long"
+	msgDescriptionTooLong    = "description_too_long"
+	msgDueDateInvalid        = "due_date_invalid"
+	msgPriorityInvalid       = "priority_invalid"
+	msgStatusInvalid         = "status_invalid"
+	msgIDsEmpty              = "ids_empty"
+	msgIDsTooMany            = "ids_too_many"
+	msgIDsDuplicate          = "ids_duplicate"
+	msgInvalidID             = "invalid_id"
+	msgTimestampInvalid      = "timestamp_invalid"
+	msgLimitInvalid          = "limit_invalid"
+	msgCursorInvalid         = "cursor_invalid"
+	msgOffsetInvalid         = "offset_invalid"
+	msgCommentRequired       = "comment_required"
+	msgCommentTooLong        = "comment_too_long"
+	msgParentNotFound        = "parent_not_found"
+	msgParentCycle           = "parent_cycle"
+	msgDependencyNotFound    = "dependency_not_found"
+	msgRecurrenceInvalid     = "recurrence_invalid"
+	msgTagTooLong            = "tag_too_long"
+	msgTagsTooMany           = "tags_too_many"
+	msgAssigneeBlank         = "assignee_blank"
+	msgWithinInvalid         = "within_invalid"
+	msgTimezoneInvalid       = "timezone_invalid"
+	msgOlderThanInvalid      = "older_than_invalid"
+	msgFieldUnknown          = "field_unknown"
+	msgFieldsEmpty           = "fields_empty"
+	msgIdempotencyKeyTooLong = "idempotency_key_too_long"
+	msgFieldRequired         = "field_required"
+	msgFieldInvalid          = "field_invalid"
+)
+
+// messageCatalog holds the built-in translations, keyed by language and then
+// message key. Entries under app.messages in the config override or extend it.
+var messageCatalog = map[string]map[string]string{
+	"en": {
+		msgTitleRequired:         "title is required and must not be blank",
+		msgTitleTooLong:          "title must be at most %d characters",
+		msgDescriptionTooLong:    "description must be at most %d characters",
+		msgDueDateInvalid:        "due_date must be an RFC3339 timestamp",
+		msgPriorityInvalid:       "priority must be one of low, medium, high",
+		msgStatusInvalid:         "status must be one of pending, in_progress, completed",
+		msgIDsEmpty:              "ids must contain at least one id",
+		msgIDsTooMany:            "ids must not contain more than %d ids",
+		msgIDsDuplicate:          "ids must not repeat id %d",
+		msgInvalidID:             "invalid id: must be a positive integer",
+		msgTimestampInvalid:      "%s must be an RFC3339 timestamp",
+		msgLimitInvalid:          "limit must be between 1 and %d",
+		msgCursorInvalid:         "cursor must be a positive task id",
+		msgOffsetInvalid:         "offset must be a non-negative integer",
+		msgCommentRequired:       "body is required and must not be blank",
+		msgCommentTooLong:        "body must be at most %d characters",
+		msgParentNotFound:        "parent_id must refer to an existing task",
+		msgParentCycle:           "parent_id would make the task its own ancestor",
+		msgDependencyNotFound:    "depends_on must refer to an existing task",
+		msgRecurrenceInvalid:     "recurrence must be one of none, daily, weekly, monthly",
+		msgTagTooLong:            "each tag must be at most %d characters",
+		msgTagsTooMany:           "a task can have at most %d tags",
+		msgAssigneeBlank:         "assignee must not be blank; send null to unassign",
+		msgWithinInvalid:         "within must be a positive duration such as 90m or 48h",
+		msgTimezoneInvalid:       "unknown time zone %q; use an IANA name such as America/New_York",
+		msgOlderThanInvalid:      "older_than must be a non-negative age such as 30d or 12h",
+		msgFieldUnknown:          "unknown field %q in fields; use any of %s",
+		msgFieldsEmpty:           "fields must name at least one field",
+		msgIdempotencyKeyTooLong: "Idempotency-Key must be at most %d characters",
+		msgFieldRequired:         "%s is required",
+		msgFieldInvalid:          "%s is invalid",
+	},
+	"es": {
+		msgTitleRequired:         "el título es obligatorio y no puede estar vacío",
+		msgTitleTooLong:          "el título debe tener como máximo %d caracteres",
+		msgDescriptionTooLong:    "la descripción debe tener como máximo %d caracteres",
+		msgDueDateInvalid:        "due_date debe ser una fecha RFC3339",
+		msgPriorityInvalid:       "priority debe ser low, medium o high",
+		msgStatusInvalid:         "status debe ser pending, in_progress o completed",
+		msgIDsEmpty:              "ids debe contener al menos un id",
+		msgIDsTooMany:            "ids no puede contener más de %d ids",
+		msgIDsDuplicate:          "ids no puede repetir el id %d",
+		msgInvalidID:             "id no válido: debe ser un entero positivo",
+		msgTimestampInvalid:      "%s debe ser una fecha RFC3339",
+		msgLimitInvalid:          "limit debe estar entre 1 y %d",
+		msgCursorInvalid:         "cursor debe ser un id de tarea positivo",
+		msgOffsetInvalid:         "offset debe ser un entero no negativo",
+		msgCommentRequired:       "body es obligatorio y no puede estar vacío",
+		msgCommentTooLong:        "body debe tener como máximo %d caracteres",
+		msgParentNotFound:        "parent_id debe referirse a una tarea existente",
+		msgParentCycle:           "parent_id haría que la tarea sea su propio ancestro",
+		msgDependencyNotFound:    "depends_on debe referirse a una tarea existente",
+		msgRecurrenceInvalid:     "recurrence debe ser none, daily, weekly o monthly",
+		msgTagTooLong:            "cada etiqueta debe tener como máximo %d caracteres",
+		msgTagsTooMany:           "una tarea puede tener como máximo %d etiquetas",
+		msgAssigneeBlank:         "assignee no puede estar vacío; envíe null para quitar la asignación",
+		msgWithinInvalid:         "within debe ser una duración positiva como 90m o 48h",
+		msgTimezoneInvalid:       "zona horaria desconocida %q; use un nombre IANA como America/New_York",
+		msgOlderThanInvalid:      "older_than debe ser una antigüedad no negativa como 30d o 12h",
+		msgFieldUnknown:          "campo desconocido %q en fields; use cualquiera de %s",
+		msgFieldsEmpty:           "fields debe nombrar al menos un campo",
+		msgIdempotencyKeyTooLong: "Idempotency-Key debe tener como máximo %d caracteres",
+		msgFieldRequired:         "%s es obligatorio",
+		msgFieldInvalid:          "%s no es válido",
+	},
+}
+
+// validationError is a client-facing error that can be rendered in the
+// caller's language. Error() always renders English.
+type validationError struct {
+	key  string
+	args []interface{}
+}
+
+func newValidationError(key string, args ...interface{}) error {
+	return &validationError{key: key, args: args}
+}
+
+// isValidationError reports whether err is a client-facing validation error.
+func isValidationError(err error) bool {
+	var verr *validationError
+	return errors.As(err, &verr)
+}
+
+func (e *validationError) Error() string {
+	return e.render(defaultLanguage)
+}
+
+func (e *validationError) render(lang string) string {
+	format, ok := lookupMessage(lang, e.key)
+	if !ok {
+		format, ok = lookupMessage(defaultLanguage, e.key)
+	}
+	if !ok {
+		return e.key
+	}
+	return fmt.Sprintf(format, e.args...)
+}
+
+func lookupMessage(lang, key string) (string, bool) {
+	if msg, ok := config().App.Messages[lang][key]; ok {
+		return msg, true
+	}
+	msg, ok := messageCatalog[lang][key]
+	return msg, ok
+}
+
+func hasLanguage(lang string) bool {
+	return len(messageCatalog[lang]) > 0 || len(config().App.Messages[lang]) > 0
+}
+
+// requestLanguage picks the best catalog language from the Accept-Language
+// header, honouring q-values and falling back from regional tags such as
+// es-MX to their base language. It returns defaultLanguage when nothing
+// matches.
+func requestLanguage(c *gin.Context) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, cand := range candidates {
+		if hasLanguage(cand.tag) {
+			return cand.tag
+		}
+		if base, _, ok := strings.Cut(cand.tag, "-"); ok && hasLanguage(base) {
+			return base
+		}
+	}
+	return defaultLanguage
+}
+
+// localize renders err in the request's language when it is a validation
+// error; any other error is returned as is.
+func localize(c *gin.Context, err error) string {
+	var verr *validationError
+	if errors.As(err, &verr) {
+		lang := requestLanguage(c)
+		c.Header("Content-Language", lang)
+		return verr.render(lang)
+	}
+	return err.Error()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLanguage(t *testing.T) {
+	setupTestRouter()
+
+	for header, want := range map[string]string{
+		"":                          "en",
+		"es":                        "es",
+		"es-MX,es;q=0.9":            "es",
+		"fr-FR, es;q=0.5, en;q=0.8": "en",
+		"de, *;q=0.1":               "en",
+		"es;q=0":                    "en",
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.Header.Set("Accept-Language", header)
+		assert.Equal(t, want, requestLanguage(c), header)
+	}
+}
+
+func TestValidationErrorIsLocalized(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewBufferString(`{"title":"  "}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "es-ES")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, "es", w.Header().Get("Content-Language"))
+	var body APIError
+	json.Unmarshal(w.Body.Bytes(), &body)
+	assert.Equal(t, errCodeValidation, body.Code)
+	assert.Equal(t, messageCatalog["es"][msgTitleRequired], body.Message)
+}
+
+func TestMessageCatalogFromConfig(t *testing.T) {
+	router := setupTestRouter()
+	config().App.Messages = map[string]map[string]string{
+		"fr": {msgTitleRequired: "le titre est obligatoire"},
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewBufferString(`{"title":""}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "fr")
+	router.ServeHTTP(w, req)
+
+	var body APIError
+	json.Unmarshal(w.Body.Bytes(), &body)
+	assert.Equal(t, "le titre est obligatoire", body.Message)
+
+	// Keys missing from the configured language fall back to English.
+	err := newValidationError(msgTitleTooLong, 5).(*validationError)
+	assert.Equal(t, "title must be at most 5 characters", err.render("fr"))
+}
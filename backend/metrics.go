@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskhub_http_requests_total",
+		Help: "HTTP requests processed, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "taskhub_http_requests_in_flight",
+		Help: "HTTP requests currently being served.",
+	})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "taskhub_http_request_duration_seconds",
+		Help:    "HTTP request latency, by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+func init() {
+	prometheus.MustRegister(httpRequestsTotal, httpRequestsInFlight, httpRequestDuration, webhookDeliveryCollector{})
+}
+
+var (
+	webhookDeliveriesPendingDesc = prometheus.NewDesc("taskhub_webhook_deliveries_pending",
+		"Webhook deliveries waiting to be sent or retried.", nil, nil)
+	webhookDeliveriesFailedDesc = prometheus.NewDesc("taskhub_webhook_deliveries_failed",
+		"Webhook deliveries that gave up after webhooks.max_attempts.", nil, nil)
+)
+
+// webhookDeliveryCollector reads the webhook_deliveries counts from the
+// database on each scrape, so they are right whichever instance queued or
+// sent the deliveries.
+type webhookDeliveryCollector struct{}
+
+func (webhookDeliveryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- webhookDeliveriesPendingDesc
+	ch <- webhookDeliveriesFailedDesc
+}
+
+func (webhookDeliveryCollector) Collect(ch chan<- prometheus.Metric) {
+	if db() == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), databaseTimeout())
+	defer cancel()
+	pending, failed, err := webhookDeliveryCounts(ctx)
+	if err != nil {
+		logger.Warn("failed to count webhook deliveries", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(webhookDeliveriesPendingDesc, prometheus.GaugeValue, float64(pending))
+	ch <- prometheus.MustNewConstMetric(webhookDeliveriesFailedDesc, prometheus.GaugeValue, float64(failed))
+}
+
+// metricsMiddleware records RED metrics for every request. Requests are
+// labelled with the route template (e.g. /api/v1/tasks/:id) rather than the
+// concrete path so ids don't blow up label cardinality.
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		httpRequestsInFlight.Inc()
+		defer httpRequestsInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequestsTotal.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
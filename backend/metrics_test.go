@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	router := setupTestRouter()
+
+	for _, path := range []string{"/api/v1/tasks/1", "/api/v1/tasks/99999", "/no/such/route"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `taskhub_http_requests_total{method="GET",route="/api/v1/tasks/:id",status="200"}`)
+	assert.Contains(t, body, `taskhub_http_requests_total{method="GET",route="/api/v1/tasks/:id",status="404"}`)
+	assert.Contains(t, body, `route="unmatched"`)
+	assert.Contains(t, body, "taskhub_http_request_duration_seconds_bucket")
+	assert.Contains(t, body, "taskhub_http_requests_in_flight")
+	assert.Contains(t, body, "taskhub_webhook_deliveries_pending 0")
+	assert.Contains(t, body, "taskhub_webhook_deliveries_failed 0")
+	assert.NotContains(t, body, "/api/v1/tasks/99999")
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+	maxRequestIDLen = 128
+)
+
+// newRequestID returns a random RFC 4122 version 4 UUID.
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// validRequestID accepts caller-supplied ids that are short and printable so
+// they can't be used to inject into headers or logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDMiddleware tags every request with an id, reusing the caller's
+// X-Request-ID when it is usable, and echoes it back in the response.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// requestID returns the id assigned by requestIDMiddleware, if any.
+func requestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// requestLogger returns the configured logger tagged with the request id, for
+// handlers that want their own log lines to correlate with the access log.
+func requestLogger(c *gin.Context) *slog.Logger {
+	if id := requestID(c); id != "" {
+		return logger.With("request_id", id)
+	}
+	return logger
+}
+
+// defaultLogSkipPaths keeps liveness probes and metric scrapes out of the
+// access log when logging.skip_paths isn't configured.
+var defaultLogSkipPaths = []string{"/api/v1/health", "/api/v1/health/live", "/api/v1/health/ready", "/metrics"}
+
+func logSkipPaths() []string {
+	if skipPaths := config().Logging.SkipPaths; len(skipPaths) > 0 {
+		return skipPaths
+	}
+	return defaultLogSkipPaths
+}
+
+// requestLoggingMiddleware writes one structured access-log line per request
+// through the configured logger, except for paths in logging.skip_paths.
+func requestLoggingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		c.Next()
+
+		for _, skip := range logSkipPaths() {
+			if path == skip {
+				return
+			}
+		}
+
+		logger.Info("request",
+			"request_id", requestID(c),
+			"method", c.Request.Method,
+			"path", path,
+			"status", c.Writer.Status(),
+			"bytes", c.Writer.Size(),
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
+
+// recoveryMiddleware turns a panic in a later handler into the standard JSON
+// 500 body. The panic value and stack trace are logged with the request id
+// and never sent to the client.
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// net/http uses this panic to abort a response on purpose.
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			requestLogger(c).Error("panic recovered", "method", c.Request.Method, "path", c.Request.URL.Path, "panic", fmt.Sprint(rec), "stack", string(debug.Stack()))
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			body := APIError{Code: errCodeInternal, Message: "internal server error"}
+			if config().App.ErrorRequestID {
+				body.RequestID = requestID(c)
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, body)
+		}()
+		c.Next()
+	}
+}
+
+const defaultMaxBodyBytes = 1 << 20
+
+func maxBodyBytes() int64 {
+	if config().App.MaxBodyBytes > 0 {
+		return config().App.MaxBodyBytes
+	}
+	return defaultMaxBodyBytes
+}
+
+// bodyLimitMiddleware caps request bodies at app.max_body_bytes. A declared
+// Content-Length over the limit is rejected up front; otherwise reads past the
+// limit fail and binding handlers answer 413 through respondBindError.
+func bodyLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := maxBodyBytes()
+		if c.Request.ContentLength > limit {
+			respondError(c, http.StatusRequestEntityTooLarge, errCodePayloadTooLarge, fmt.Sprintf("request body must not exceed %d bytes", limit))
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+const defaultRequestTimeoutSeconds = 30
+
+func requestTimeout() time.Duration {
+	if config().App.RequestTimeoutSeconds > 0 {
+		return time.Duration(config().App.RequestTimeoutSeconds) * time.Second
+	}
+	return defaultRequestTimeoutSeconds * time.Second
+}
+
+// untimedRoutes stream for as long as the client stays connected, or run
+// maintenance that takes as long as the database needs, so they get no
+// request deadline. A CSV export cut off at the deadline would still end on
+// a row boundary with a 200 and look complete, so it streams to the end.
+var untimedRoutes = map[string]bool{
+	"/api/v1/tasks/stream":             true,
+	"/api/v1/tasks/events":             true,
+	"/api/v1/tasks/export.csv":         true,
+	"/api/v1/admin/generate":           true,
+	"/api/v1/admin/maintenance/vacuum": true,
+}
+
+// requestTimeoutMiddleware replaces the request context with one that expires
+// after app.request_timeout_seconds. Database calls made with it are canceled
+// at the deadline, and respondInternalError turns the failure into a 503.
+func requestTimeoutMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if untimedRoutes[c.FullPath()] {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), requestTimeout())
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestIDGenerated(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/health", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Regexp(t, uuidPattern, w.Header().Get("X-Request-ID"))
+}
+
+func TestRequestIDPropagated(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/health", nil)
+	req.Header.Set("X-Request-ID", "upstream-123")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "upstream-123", w.Header().Get("X-Request-ID"))
+}
+
+func TestRequestIDRejectsUnsafeValues(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/health", nil)
+	req.Header.Set("X-Request-ID", "has spaces in it")
+	router.ServeHTTP(w, req)
+
+	assert.Regexp(t, uuidPattern, w.Header().Get("X-Request-ID"))
+}
+
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	l, err := newLogger(&buf, "info", "json")
+	assert.NoError(t, err)
+	previous := logger
+	logger = l
+	t.Cleanup(func() { logger = previous })
+	return &buf
+}
+
+func TestRequestLoggingMiddleware(t *testing.T) {
+	router := setupTestRouter()
+	logs := captureLogs(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks", nil)
+	req.Header.Set("X-Request-ID", "trace-42")
+	router.ServeHTTP(w, req)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(bytes.TrimSpace(logs.Bytes()), &entry))
+	assert.Equal(t, "request", entry["msg"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/api/v1/tasks", entry["path"])
+	assert.Equal(t, float64(200), entry["status"])
+	assert.Equal(t, float64(w.Body.Len()), entry["bytes"])
+	assert.Contains(t, entry, "duration_ms")
+	assert.Equal(t, "trace-42", entry["request_id"])
+}
+
+func TestRequestLoggingSkipsPaths(t *testing.T) {
+	router := setupTestRouter()
+	logs := captureLogs(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/health", nil)
+	router.ServeHTTP(w, req)
+	assert.Empty(t, logs.String())
+
+	config().Logging.SkipPaths = []string{"/api/v1/tasks"}
+	for _, path := range []string{"/api/v1/health", "/api/v1/tasks"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+	}
+	assert.Equal(t, 1, strings.Count(logs.String(), "\n"))
+	assert.Contains(t, logs.String(), `"path":"/api/v1/health"`)
+}
+
+func TestBodyLimit(t *testing.T) {
+	router := setupTestRouter()
+	config().App.MaxBodyBytes = 64
+
+	body := `{"title":"` + strings.Repeat("x", 100) + `"}`
+
+	// Declared length over the limit is rejected before the handler runs.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 413, w.Code)
+	assert.Contains(t, w.Body.String(), errCodePayloadTooLarge)
+
+	// Chunked bodies with no declared length are cut off while binding.
+	for method, path := range map[string]string{"POST": "/api/v1/tasks", "PUT": "/api/v1/tasks/1"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.ContentLength = -1
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 413, w.Code, method)
+		assert.Contains(t, w.Body.String(), "must not exceed 64 bytes", method)
+	}
+
+	// Small bodies are unaffected.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/tasks", strings.NewReader(`{"title":"ok"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	router := setupTestRouter()
+	assert.Equal(t, defaultRequestTimeoutSeconds*time.Second, requestTimeout())
+
+	// A deadline that has already passed cancels the handler's first query.
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/api/v1/tasks", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 503, w.Code)
+	assert.Contains(t, w.Body.String(), errCodeTimeout)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestRequestTimeoutSkipsUntimedRoutes(t *testing.T) {
+	setupTestRouter()
+	router := gin.New()
+	router.Use(requestTimeoutMiddleware())
+	deadlines := map[string]bool{}
+	record := func(c *gin.Context) {
+		_, ok := c.Request.Context().Deadline()
+		deadlines[c.FullPath()] = ok
+	}
+	router.GET("/api/v1/tasks", record)
+	router.GET("/api/v1/tasks/export.csv", record)
+
+	for _, path := range []string{"/api/v1/tasks", "/api/v1/tasks/export.csv"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.True(t, deadlines["/api/v1/tasks"])
+	assert.False(t, deadlines["/api/v1/tasks/export.csv"], "a CSV export streams to the end")
+}
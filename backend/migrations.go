@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migration is one step of schema evolution. Both dialects must end up with
+// the same tables and columns. Migrations are append-only: never edit one
+// that has shipped, add a new one instead.
+type migration struct {
+	Version  int
+	Name     string
+	SQLite   []string
+	Postgres []string
+}
+
+// migrations are applied in order on startup; versions must increase by one.
+// Migration 1 is the original five-column tasks table, so a database created
+// before migrations existed is brought forward column by column.
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		SQLite: []string{`
+	CREATE TABLE IF NOT EXISTS tasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		description TEXT,
+		status TEXT DEFAULT 'pending',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`},
+		Postgres: []string{`
+	CREATE TABLE IF NOT EXISTS tasks (
+		id SERIAL PRIMARY KEY,
+		title TEXT NOT NULL,
+		description TEXT,
+		status TEXT DEFAULT 'pending',
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);`},
+	},
+	{
+		Version:  2,
+		Name:     "tasks.due_date",
+		SQLite:   []string{"ALTER TABLE tasks ADD COLUMN due_date DATETIME"},
+		Postgres: []string{"ALTER TABLE tasks ADD COLUMN due_date TEXT"},
+	},
+	{
+		Version:  3,
+		Name:     "tasks.priority",
+		SQLite:   []string{"ALTER TABLE tasks ADD COLUMN priority TEXT DEFAULT 'medium'"},
+		Postgres: []string{"ALTER TABLE tasks ADD COLUMN priority TEXT DEFAULT 'medium'"},
+	},
+	{
+		Version: 4,
+		Name:    "tasks.deleted_at",
+		SQLite: []string{
+			"ALTER TABLE tasks ADD COLUMN deleted_at DATETIME",
+			"CREATE INDEX IF NOT EXISTS idx_tasks_active ON tasks (status, due_date) WHERE " + activeTaskPredicate + " AND " + notDeletedPredicate,
+		},
+		Postgres: []string{
+			"ALTER TABLE tasks ADD COLUMN deleted_at TIMESTAMPTZ",
+			"CREATE INDEX IF NOT EXISTS idx_tasks_active ON tasks (status, due_date) WHERE " + activeTaskPredicate + " AND " + notDeletedPredicate,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "tasks.created_by",
+		SQLite: []string{
+			"ALTER TABLE tasks ADD COLUMN created_by TEXT",
+			"ALTER TABLE tasks ADD COLUMN updated_by TEXT",
+		},
+		Postgres: []string{
+			"ALTER TABLE tasks ADD COLUMN created_by TEXT",
+			"ALTER TABLE tasks ADD COLUMN updated_by TEXT",
+		},
+	},
+	{
+		Version: 6,
+		Name:    "users",
+		SQLite: []string{`
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`},
+		Postgres: []string{`
+	CREATE TABLE IF NOT EXISTS users (
+		id SERIAL PRIMARY KEY,
+		username TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);`},
+	},
+	{
+		Version:  7,
+		Name:     "tasks.owner_id",
+		SQLite:   []string{"ALTER TABLE tasks ADD COLUMN owner_id TEXT"},
+		Postgres: []string{"ALTER TABLE tasks ADD COLUMN owner_id TEXT"},
+	},
+	{
+		Version: 8,
+		Name:    "tasks.updated_at",
+		SQLite: []string{
+			"ALTER TABLE tasks ADD COLUMN updated_at DATETIME",
+			"UPDATE tasks SET updated_at = created_at",
+		},
+		Postgres: []string{
+			"ALTER TABLE tasks ADD COLUMN updated_at TIMESTAMPTZ",
+			"UPDATE tasks SET updated_at = created_at",
+		},
+	},
+	{
+		Version:  9,
+		Name:     "tasks.version",
+		SQLite:   []string{"ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1"},
+		Postgres: []string{"ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1"},
+	},
+	{
+		Version: 10,
+		Name:    "comments",
+		SQLite: []string{`
+	CREATE TABLE comments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		body TEXT NOT NULL,
+		author TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+			"CREATE INDEX idx_comments_task ON comments (task_id, id)",
+		},
+		Postgres: []string{`
+	CREATE TABLE comments (
+		id SERIAL PRIMARY KEY,
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		body TEXT NOT NULL,
+		author TEXT,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);`,
+			"CREATE INDEX idx_comments_task ON comments (task_id, id)",
+		},
+	},
+	{
+		Version: 11,
+		Name:    "tasks.parent_id",
+		SQLite: []string{
+			"ALTER TABLE tasks ADD COLUMN parent_id INTEGER REFERENCES tasks (id) ON DELETE SET NULL",
+			"CREATE INDEX idx_tasks_parent ON tasks (parent_id)",
+		},
+		Postgres: []string{
+			"ALTER TABLE tasks ADD COLUMN parent_id INTEGER REFERENCES tasks (id) ON DELETE SET NULL",
+			"CREATE INDEX idx_tasks_parent ON tasks (parent_id)",
+		},
+	},
+	{
+		Version: 12,
+		Name:    "task_dependencies",
+		SQLite: []string{`
+	CREATE TABLE task_dependencies (
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		depends_on_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (task_id, depends_on_id)
+	);`,
+			"CREATE INDEX idx_task_dependencies_depends_on ON task_dependencies (depends_on_id)",
+		},
+		Postgres: []string{`
+	CREATE TABLE task_dependencies (
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		depends_on_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (task_id, depends_on_id)
+	);`,
+			"CREATE INDEX idx_task_dependencies_depends_on ON task_dependencies (depends_on_id)",
+		},
+	},
+	{
+		Version: 13,
+		Name:    "tasks.recurrence",
+		SQLite: []string{
+			"ALTER TABLE tasks ADD COLUMN recurrence TEXT NOT NULL DEFAULT 'none'",
+			"ALTER TABLE tasks ADD COLUMN next_occurrence_id INTEGER",
+		},
+		Postgres: []string{
+			"ALTER TABLE tasks ADD COLUMN recurrence TEXT NOT NULL DEFAULT 'none'",
+			"ALTER TABLE tasks ADD COLUMN next_occurrence_id INTEGER",
+		},
+	},
+	{
+		Version: 14,
+		Name:    "tasks.completed_at",
+		SQLite: []string{
+			"ALTER TABLE tasks ADD COLUMN completed_at DATETIME",
+			"UPDATE tasks SET completed_at = updated_at WHERE status = 'completed'",
+		},
+		Postgres: []string{
+			"ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMPTZ",
+			"UPDATE tasks SET completed_at = updated_at WHERE status = 'completed'",
+		},
+	},
+	{
+		Version: 15,
+		Name:    "tags",
+		SQLite: []string{`
+	CREATE TABLE tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE
+	);`, `
+	CREATE TABLE task_tags (
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+		PRIMARY KEY (task_id, tag_id)
+	);`,
+			"CREATE INDEX idx_task_tags_tag ON task_tags (tag_id)",
+		},
+		Postgres: []string{`
+	CREATE TABLE tags (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE
+	);`, `
+	CREATE TABLE task_tags (
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+		PRIMARY KEY (task_id, tag_id)
+	);`,
+			"CREATE INDEX idx_task_tags_tag ON task_tags (tag_id)",
+		},
+	},
+	{
+		Version: 16,
+		Name:    "tasks.archived",
+		SQLite: []string{
+			"ALTER TABLE tasks ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE",
+		},
+		Postgres: []string{
+			"ALTER TABLE tasks ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE",
+		},
+	},
+	{
+		Version: 17,
+		Name:    "idempotency_keys",
+		SQLite: []string{`
+	CREATE TABLE idempotency_keys (
+		idempotency_key TEXT NOT NULL,
+		owner TEXT NOT NULL DEFAULT '',
+		request_hash TEXT NOT NULL,
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		response TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (idempotency_key, owner)
+	);`,
+			"CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at)",
+		},
+		Postgres: []string{`
+	CREATE TABLE idempotency_keys (
+		idempotency_key TEXT NOT NULL,
+		owner TEXT NOT NULL DEFAULT '',
+		request_hash TEXT NOT NULL,
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		response TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (idempotency_key, owner)
+	);`,
+			"CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at)",
+		},
+	},
+	{
+		Version: 18,
+		Name:    "tasks.position",
+		SQLite: []string{
+			"ALTER TABLE tasks ADD COLUMN position INTEGER",
+		},
+		Postgres: []string{
+			"ALTER TABLE tasks ADD COLUMN position INTEGER",
+		},
+	},
+	{
+		Version: 19,
+		Name:    "tasks.assignee",
+		SQLite: []string{
+			"ALTER TABLE tasks ADD COLUMN assignee TEXT",
+			"CREATE INDEX idx_tasks_assignee ON tasks (assignee)",
+		},
+		Postgres: []string{
+			"ALTER TABLE tasks ADD COLUMN assignee TEXT",
+			"CREATE INDEX idx_tasks_assignee ON tasks (assignee)",
+		},
+	},
+	{
+		Version: 20,
+		Name:    "webhook_deliveries",
+		SQLite: []string{`
+	CREATE TABLE webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		event TEXT NOT NULL,
+		body TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME NOT NULL,
+		last_error TEXT,
+		failed_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+			"CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (failed_at, next_attempt_at)",
+		},
+		Postgres: []string{`
+	CREATE TABLE webhook_deliveries (
+		id SERIAL PRIMARY KEY,
+		url TEXT NOT NULL,
+		event TEXT NOT NULL,
+		body TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMPTZ NOT NULL,
+		last_error TEXT,
+		failed_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);`,
+			"CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (failed_at, next_attempt_at)",
+		},
+	},
+	{
+		Version: 21,
+		Name:    "task_positions",
+		SQLite: []string{`
+	CREATE TABLE task_positions (
+		scope TEXT NOT NULL,
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (scope, task_id)
+	);`,
+			"CREATE INDEX idx_task_positions_task ON task_positions (task_id)",
+		},
+		Postgres: []string{`
+	CREATE TABLE task_positions (
+		scope TEXT NOT NULL,
+		task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (scope, task_id)
+	);`,
+			"CREATE INDEX idx_task_positions_task ON task_positions (task_id)",
+		},
+	},
+}
+
+const createMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+// latestSchemaVersion is the version the database reaches once every
+// migration has been applied.
+func latestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// schemaVersion returns the highest applied migration version, or 0.
+func schemaVersion(ctx context.Context, q dbtx) (int, error) {
+	var version sql.NullInt64
+	if err := q.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// migrate brings the database up to latestSchemaVersion. Each migration runs
+// in its own transaction together with its schema_migrations row, so a failed
+// migration leaves the database at the previous version and startup aborts.
+func migrate(conn *sql.DB) error {
+	if _, err := conn.Exec(createMigrationsTable); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	current, err := schemaVersion(context.Background(), conn)
+	if err != nil {
+		return err
+	}
+	if latest := latestSchemaVersion(); current > latest {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := applyMigration(conn, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		logger.Info("applied migration", "version", m.Version, "name", m.Name)
+	}
+	return nil
+}
+
+func applyMigration(conn *sql.DB, m migration) error {
+	statements := m.SQLite
+	if isPostgres() {
+		statements = m.Postgres
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, m.Name)
+		assert.NotEmpty(t, m.SQLite, m.Name)
+		assert.NotEmpty(t, m.Postgres, m.Name)
+	}
+}
+
+func TestMigrateRecordsVersionsAndIsIdempotent(t *testing.T) {
+	setupTestRouter()
+
+	version, err := schemaVersion(context.Background(), db())
+	assert.NoError(t, err)
+	assert.Equal(t, latestSchemaVersion(), version)
+
+	assert.NoError(t, migrate(db()))
+
+	var applied int
+	assert.NoError(t, db().QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&applied))
+	assert.Equal(t, len(migrations), applied)
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	setupTestRouter()
+
+	original := migrations
+	defer func() { migrations = original }()
+	next := latestSchemaVersion() + 1
+	migrations = append(append([]migration{}, original...), migration{
+		Version:  next,
+		Name:     "broken",
+		SQLite:   []string{"CREATE TABLE half_done (id INTEGER)", "THIS IS NOT SQL"},
+		Postgres: []string{"THIS IS NOT SQL"},
+	})
+
+	err := migrate(db())
+	assert.ErrorContains(t, err, "broken")
+
+	version, err := schemaVersion(context.Background(), db())
+	assert.NoError(t, err)
+	assert.Equal(t, next-1, version)
+
+	var name string
+	err = db().QueryRow("SELECT name FROM sqlite_master WHERE name = 'half_done'").Scan(&name)
+	assert.Error(t, err, "the partial migration must be rolled back")
+}
+
+func TestMigrateRefusesNewerDatabase(t *testing.T) {
+	setupTestRouter()
+
+	_, err := db().Exec("INSERT INTO schema_migrations (version, name) VALUES (?, 'from the future')", latestSchemaVersion()+1)
+	assert.NoError(t, err)
+
+	assert.ErrorContains(t, migrate(db()), "newer than this build")
+}
+
+func TestMigrateUpgradesBaselineDatabase(t *testing.T) {
+	setupTestRouter()
+
+	// A database as the app created it before migrations existed: the
+	// five-column tasks table with its sample rows and no schema_migrations.
+	path := filepath.Join(t.TempDir(), "baseline.db")
+	baseline, err := sql.Open("sqlite3", path)
+	assert.NoError(t, err)
+	_, err = baseline.Exec(`
+	CREATE TABLE IF NOT EXISTS tasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		description TEXT,
+		status TEXT DEFAULT 'pending',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO tasks (title, description, status) VALUES
+		('Setup Development Environment', 'Install and configure development tools', 'completed'),
+		('Deploy to Production', 'Deploy application to production environment', 'pending');`)
+	assert.NoError(t, err)
+	assert.NoError(t, baseline.Close())
+
+	config().Database.Path = path
+	config().Database.Seed = false
+	assert.NoError(t, initDatabase())
+	defer db().Close()
+
+	version, err := schemaVersion(context.Background(), db())
+	assert.NoError(t, err)
+	assert.Equal(t, latestSchemaVersion(), version)
+
+	report, err := checkSchema(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, report.OK, "%+v", report)
+
+	var count int
+	assert.NoError(t, db().QueryRow("SELECT COUNT(*) FROM tasks WHERE "+notDeletedPredicate+" AND priority = 'medium'").Scan(&count))
+	assert.Equal(t, 2, count, "existing rows keep their data and get the column defaults")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TaskList is the XML document for a list of tasks; in JSON a list is a bare
+// array.
+type TaskList struct {
+	XMLName xml.Name `xml:"tasks"`
+	Tasks   []Task   `xml:"task"`
+}
+
+// wantsXML reports whether the Accept header asks for XML. Anything else,
+// including types the API doesn't offer, gets JSON rather than 406.
+func wantsXML(c *gin.Context) bool {
+	switch c.NegotiateFormat(gin.MIMEJSON, gin.MIMEXML, gin.MIMEXML2) {
+	case gin.MIMEXML, gin.MIMEXML2:
+		return true
+	}
+	return false
+}
+
+// respondRead writes obj as XML or JSON following the Accept header.
+func respondRead(c *gin.Context, status int, obj interface{}) {
+	if wantsXML(c) {
+		c.XML(status, obj)
+		return
+	}
+	c.JSON(status, obj)
+}
+
+// respondTasks is respondRead for a list of tasks.
+func respondTasks(c *gin.Context, status int, tasks []Task) {
+	inRequestZone(c, tasks)
+	if wantsXML(c) {
+		c.XML(status, TaskList{Tasks: tasks})
+		return
+	}
+	c.JSON(status, tasks)
+}
+
+// encodeTask renders a single task for the Accept header, restricted to
+// fields in JSON, and returns the body with its content type.
+func encodeTask(c *gin.Context, task Task, fields []string) ([]byte, string, error) {
+	taskInRequestZone(c, &task)
+	if wantsXML(c) {
+		body, err := xml.Marshal(task)
+		return body, "application/xml; charset=utf-8", err
+	}
+	if fields != nil {
+		selected, err := selectFields(task, fields)
+		if err != nil {
+			return nil, "", err
+		}
+		body, err := json.Marshal(selected)
+		return body, "application/json; charset=utf-8", err
+	}
+	body, err := json.Marshal(task)
+	return body, "application/json; charset=utf-8", err
+}
+
+// encodeTasks renders a task list for the Accept header and returns the body
+// with its content type. Sparse fieldsets only apply to JSON; XML always
+// carries whole tasks.
+func encodeTasks(c *gin.Context, tasks []Task, fields []string) ([]byte, string, error) {
+	inRequestZone(c, tasks)
+	if wantsXML(c) {
+		body, err := xml.Marshal(TaskList{Tasks: tasks})
+		return body, "application/xml; charset=utf-8", err
+	}
+	body, err := marshalTasks(tasks, fields)
+	return body, "application/json; charset=utf-8", err
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXMLContentNegotiation(t *testing.T) {
+	router := setupTestRouter()
+	created := createTestTask(t, router, Task{Title: "Partner feed", Tags: []string{"b2b"}})
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", accept)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/v1/tasks?tag=b2b", "application/xml")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/xml")
+	var list TaskList
+	assert.NoError(t, xml.Unmarshal(w.Body.Bytes(), &list))
+	if assert.Len(t, list.Tasks, 1) {
+		assert.Equal(t, created.ID, list.Tasks[0].ID)
+		assert.Equal(t, []string{"b2b"}, list.Tasks[0].Tags)
+	}
+
+	w = get("/api/v1/tasks/"+strconv.Itoa(created.ID), "text/xml")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "<task><id>"+strconv.Itoa(created.ID)+"</id><title>Partner feed</title>")
+
+	w = get("/api/v1/health/live", "application/xml")
+	assert.Contains(t, w.Body.String(), "<health><status>alive</status>")
+
+	// Types the API doesn't offer fall back to JSON instead of 406.
+	for _, accept := range []string{"", "image/png", "application/json"} {
+		w = get("/api/v1/tasks/"+strconv.Itoa(created.ID), accept)
+		assert.Equal(t, 200, w.Code)
+		var task Task
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &task), accept)
+		assert.Equal(t, "Partner feed", task.Title)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// pageLimit reads ?limit=, falling back to defaultPageLimit.
+func pageLimit(c *gin.Context) (int, error) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return defaultPageLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		return 0, newValidationError(msgLimitInvalid, maxPageLimit)
+	}
+	return limit, nil
+}
+
+// cursorPage reads keyset pagination parameters. Cursor mode is selected by
+// the presence of ?cursor=; an empty cursor starts from the newest task, and
+// otherwise the page holds tasks with ids below the cursor. Unlike offsets,
+// cursors don't drift when tasks are created or deleted between pages.
+func cursorPage(c *gin.Context) (cursor, limit int, ok bool, err error) {
+	raw, ok := c.GetQuery("cursor")
+	if !ok {
+		return 0, 0, false, nil
+	}
+	if raw != "" {
+		cursor, err = strconv.Atoi(raw)
+		if err != nil || cursor <= 0 {
+			return 0, 0, true, newValidationError(msgCursorInvalid)
+		}
+	}
+	limit, err = pageLimit(c)
+	return cursor, limit, true, err
+}
+
+// offsetPage reads ?limit= and ?offset=. Offset mode is selected when either
+// is present and no cursor is given.
+func offsetPage(c *gin.Context) (limit, offset int, ok bool, err error) {
+	_, hasLimit := c.GetQuery("limit")
+	rawOffset, hasOffset := c.GetQuery("offset")
+	if !hasLimit && !hasOffset {
+		return 0, 0, false, nil
+	}
+	if hasOffset {
+		offset, err = strconv.Atoi(rawOffset)
+		if err != nil || offset < 0 {
+			return 0, 0, true, newValidationError(msgOffsetInvalid)
+		}
+	}
+	limit, err = pageLimit(c)
+	return limit, offset, true, err
+}
+
+// setPageHeaders describes an offset page without changing the JSON array
+// body that existing clients depend on.
+func setPageHeaders(c *gin.Context, total, limit, offset int) {
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.Header("X-Page-Limit", strconv.Itoa(limit))
+	c.Header("X-Page-Offset", strconv.Itoa(offset))
+}
+
+// nextPageLink builds a Link header pointing at the page after cursor, keeping
+// the request's other query parameters.
+func nextPageLink(u *url.URL, cursor int) string {
+	next := *u
+	query := next.Query()
+	query.Set("cursor", strconv.Itoa(cursor))
+	next.RawQuery = query.Encode()
+	return "<" + next.RequestURI() + `>; rel="next"`
+}
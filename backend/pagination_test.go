@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var nextLinkPattern = regexp.MustCompile(`^<([^>]+)>; rel="next"$`)
+
+func TestGetTasksCursorPagination(t *testing.T) {
+	router := setupTestRouter()
+	for _, title := range []string{"One", "Two", "Three", "Four", "Five"} {
+		createTestTask(t, router, Task{Title: title})
+	}
+	all := listTestTasks(t, router, "")
+
+	var seen []int
+	path := "/api/v1/tasks?cursor=&limit=2"
+	for pages := 0; path != ""; pages++ {
+		if !assert.Less(t, pages, len(all), "pagination does not terminate") {
+			return
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+
+		var page []Task
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.LessOrEqual(t, len(page), 2)
+		for _, task := range page {
+			seen = append(seen, task.ID)
+		}
+
+		if pages == 0 {
+			// Tasks created mid-scroll are newer than the cursor and don't
+			// shift the pages that follow.
+			createTestTask(t, router, Task{Title: "Late arrival"})
+		}
+
+		path = ""
+		if link := w.Header().Get("Link"); link != "" {
+			match := nextLinkPattern.FindStringSubmatch(link)
+			if assert.NotNil(t, match, link) {
+				path = match[1]
+			}
+		}
+	}
+
+	assert.Len(t, seen, len(all))
+	for i := 1; i < len(seen); i++ {
+		assert.Greater(t, seen[i-1], seen[i], "ids are strictly descending")
+	}
+}
+
+func TestGetTasksCursorPaginationKeepsFilters(t *testing.T) {
+	router := setupTestRouter()
+	createTestTask(t, router, Task{Title: "Done", Status: "completed"})
+	createTestTask(t, router, Task{Title: "Also done", Status: "completed"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks?status=completed&cursor=&limit=1", nil)
+	router.ServeHTTP(w, req)
+
+	match := nextLinkPattern.FindStringSubmatch(w.Header().Get("Link"))
+	if assert.NotNil(t, match) {
+		assert.Contains(t, match[1], "status=completed")
+		assert.Contains(t, match[1], "limit=1")
+	}
+}
+
+func TestGetTasksCursorPaginationRejectsBadParams(t *testing.T) {
+	router := setupTestRouter()
+
+	for _, query := range []string{"?cursor=abc", "?cursor=-1", "?cursor=&limit=0", "?cursor=&limit=1000"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/tasks"+query, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code, query)
+	}
+}
+
+func TestGetTasksOffsetPaginationHeaders(t *testing.T) {
+	router := setupTestRouter()
+	// The seed data holds one completed task, "Setup Development Environment".
+	for _, title := range []string{"One", "Two", "Three", "Four"} {
+		createTestTask(t, router, Task{Title: title, Status: "completed"})
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks?status=completed&limit=3&offset=2", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	assert.Equal(t, "5", w.Header().Get("X-Total-Count"), "total respects the status filter")
+	assert.Equal(t, "3", w.Header().Get("X-Page-Limit"))
+	assert.Equal(t, "2", w.Header().Get("X-Page-Offset"))
+
+	var page []Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	if assert.Len(t, page, 3) {
+		assert.Equal(t, "Two", page[0].Title)
+		assert.Equal(t, "One", page[1].Title)
+	}
+
+	// Unpaginated listings are unchanged.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks", nil)
+	router.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("X-Total-Count"))
+
+	for _, query := range []string{"?offset=-1", "?offset=x", "?limit=0"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/tasks"+query, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code, query)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultRateLimitPerMinute = 100
+	// rateLimitIdleTTL is how long an untouched bucket is kept; by then it has
+	// refilled completely, so dropping it changes nothing for the client.
+	rateLimitIdleTTL = 10 * time.Minute
+)
+
+// rateLimitExemptPrefix keeps health checks out of rate limiting so probes
+// never get throttled.
+const rateLimitExemptPrefix = "/api/v1/health"
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a set of token buckets keyed by client, safe for concurrent
+// use. Idle buckets are swept out as requests come in.
+type rateLimiter struct {
+	mu          sync.Mutex
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+// rateLimitResult is the state of a bucket after a request took, or failed
+// to take, a token from it.
+type rateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next token when the request was
+	// refused; Reset is how long until the bucket is full again.
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// allow takes a token from key's bucket, which refills at perMinute tokens a
+// minute up to burst. When the bucket is empty it reports how long until the
+// next token is available.
+func (l *rateLimiter) allow(key string, perMinute, burst int) rateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastCleanup) > rateLimitIdleTTL {
+		l.cleanup(now)
+	}
+
+	rate := float64(perMinute) / 60
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	result := rateLimitResult{Limit: burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second))
+	return result
+}
+
+func (l *rateLimiter) cleanup(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > rateLimitIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastCleanup = now
+}
+
+// rateLimitMiddleware throttles each client with a token bucket configured by
+// security.rate_limit and answers 429 with Retry-After once it is empty. A
+// request carrying a valid API key draws from that key's bucket; anything
+// else from its IP's. Every throttled response reports the bucket in the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers,
+// the last in seconds until it is full again.
+func rateLimitMiddleware() gin.HandlerFunc {
+	limiter := newRateLimiter()
+	return func(c *gin.Context) {
+		settings := config().Security.RateLimit
+		if !settings.Enabled || strings.HasPrefix(c.Request.URL.Path, rateLimitExemptPrefix) {
+			c.Next()
+			return
+		}
+
+		key, tier := rateLimitClient(c, settings)
+		result := limiter.allow(key, tier.RequestsPerMinute, tier.Burst)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+			respondError(c, http.StatusTooManyRequests, errCodeRateLimited, "rate limit exceeded")
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitClient returns the bucket key and limits for a request: its API
+// key's tier when it carries a valid one in api_key auth mode, otherwise the
+// per-IP limit.
+func rateLimitClient(c *gin.Context, settings RateLimitConfig) (string, RateLimitTier) {
+	ip := RateLimitTier{RequestsPerMinute: settings.RequestsPerMinute, Burst: settings.Burst}
+	if ip.RequestsPerMinute <= 0 {
+		ip.RequestsPerMinute = defaultRateLimitPerMinute
+	}
+	if ip.Burst <= 0 {
+		ip.Burst = ip.RequestsPerMinute
+	}
+
+	if config().Security.AuthMode == authModeAPIKey {
+		if key := c.GetHeader(apiKeyHeader); key != "" {
+			if index := matchAPIKey(key, config().Security.APIKeys); index >= 0 {
+				id := apiKeyID(index)
+				tier := settings.APIKeyLimits[id]
+				base := withTierDefaults(settings.APIKey, ip)
+				return id, withTierDefaults(tier, base)
+			}
+		}
+	}
+	return c.ClientIP(), ip
+}
+
+// withTierDefaults fills the unset limits of tier from fallback.
+func withTierDefaults(tier, fallback RateLimitTier) RateLimitTier {
+	if tier.RequestsPerMinute <= 0 {
+		tier.RequestsPerMinute = fallback.RequestsPerMinute
+	}
+	if tier.Burst <= 0 {
+		tier.Burst = fallback.Burst
+	}
+	return tier
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterRefills(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newRateLimiter()
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		result := limiter.allow("1.2.3.4", 60, 3)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2-i, result.Remaining)
+	}
+	result := limiter.allow("1.2.3.4", 60, 3)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// Other clients have their own bucket.
+	assert.True(t, limiter.allow("5.6.7.8", 60, 3).Allowed)
+
+	now = now.Add(time.Second)
+	assert.True(t, limiter.allow("1.2.3.4", 60, 3).Allowed)
+}
+
+func TestRateLimiterCleansUpIdleBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newRateLimiter()
+	limiter.now = func() time.Time { return now }
+
+	limiter.allow("idle", 60, 3)
+	now = now.Add(rateLimitIdleTTL + time.Minute)
+	limiter.allow("active", 60, 3)
+
+	assert.NotContains(t, limiter.buckets, "idle")
+	assert.Contains(t, limiter.buckets, "active")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	router := setupTestRouter()
+	config().Security.RateLimit.Enabled = true
+	config().Security.RateLimit.RequestsPerMinute = 60
+	config().Security.RateLimit.Burst = 2
+
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/tasks", nil)
+		router.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+		if w.Code == 429 {
+			assert.Equal(t, "1", w.Header().Get("Retry-After"))
+			assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+			assert.Equal(t, "2", w.Header().Get("X-RateLimit-Reset"))
+		}
+	}
+	assert.Equal(t, []int{200, 200, 429}, codes)
+
+	// Health checks are never throttled.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/health", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestRateLimitPerAPIKey(t *testing.T) {
+	router := setupTestRouter()
+	config().Security.AuthMode = authModeAPIKey
+	config().Security.APIKeys = []string{"basic-key", "premium-key"}
+	config().Security.RateLimit = RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 60,
+		Burst:             1,
+		APIKey:            RateLimitTier{RequestsPerMinute: 60, Burst: 2},
+		APIKeyLimits:      map[string]RateLimitTier{"api-key-2": {Burst: 4}},
+	}
+
+	send := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/tasks", nil)
+		if key != "" {
+			req.Header.Set(apiKeyHeader, key)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Each key has its own bucket, sized by its tier, whatever its IP.
+	for key, limit := range map[string]int{"basic-key": 2, "premium-key": 4} {
+		for i := 0; i < limit; i++ {
+			w := send(key)
+			assert.Equal(t, 200, w.Code, key)
+			assert.Equal(t, strconv.Itoa(limit), w.Header().Get("X-RateLimit-Limit"), key)
+			assert.Equal(t, strconv.Itoa(limit-i-1), w.Header().Get("X-RateLimit-Remaining"), key)
+		}
+		assert.Equal(t, 429, send(key).Code, key)
+	}
+
+	// Requests without a valid key are limited by IP.
+	assert.Equal(t, 401, send("").Code)
+	assert.Equal(t, 429, send("wrong-key").Code)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+const recurrenceNone = "none"
+
+// recurrenceSteps maps each recurrence rule to how far ahead the next
+// occurrence is due.
+var recurrenceSteps = map[string]func(time.Time) time.Time{
+	"daily":   func(t time.Time) time.Time { return t.AddDate(0, 0, 1) },
+	"weekly":  func(t time.Time) time.Time { return t.AddDate(0, 0, 7) },
+	"monthly": func(t time.Time) time.Time { return t.AddDate(0, 1, 0) },
+}
+
+const defaultRecurrenceInterval = time.Minute
+
+// recurrenceBatchSize bounds how many completed tasks one tick handles; the
+// rest are picked up on the next tick.
+const recurrenceBatchSize = 100
+
+func recurrenceInterval() time.Duration {
+	if seconds := config().Recurrence.IntervalSeconds; seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultRecurrenceInterval
+}
+
+// nextDueDate advances the due date by one step of the rule, and keeps going
+// until it is in the future so a task completed late doesn't come back
+// already overdue. Tasks without a due date are due one step from now.
+func nextDueDate(dueDate *string, step func(time.Time) time.Time, now time.Time) *string {
+	base := now
+	if dueDate != nil {
+		if due, err := time.Parse(time.RFC3339, *dueDate); err == nil {
+			base = due
+		}
+	}
+	next := step(base)
+	for !next.After(now) {
+		next = step(next)
+	}
+	formatted := next.UTC().Format(time.RFC3339)
+	return &formatted
+}
+
+// startRecurrence generates pending occurrences once at startup, to catch up
+// on anything completed while the server was down, and then on every tick.
+func startRecurrence(ctx context.Context) {
+	job := func() {
+		created, err := generateRecurrences(ctx, time.Now())
+		if err != nil {
+			logger.Error("recurring task generation failed", "error", err)
+			return
+		}
+		if created > 0 {
+			logger.Info("created recurring task occurrences", "count", created)
+		}
+	}
+	go func() {
+		job()
+		runEvery(ctx, recurrenceInterval(), job)
+	}()
+}
+
+// generateRecurrences creates the next occurrence of every completed recurring
+// task that doesn't have one yet and returns how many it created.
+//
+// next_occurrence_id is the marker that prevents duplicates: it is claimed in
+// the same transaction that inserts the occurrence, and only while it is still
+// NULL, so a restart or a second instance running the job can never generate
+// the same occurrence twice. Reopening and completing a task again doesn't
+// create another one either.
+func generateRecurrences(ctx context.Context, now time.Time) (int, error) {
+	due, err := queryTasks(ctx, db(), "SELECT "+taskColumns+" FROM tasks WHERE recurrence != ? AND status = 'completed' AND next_occurrence_id IS NULL AND "+notDeletedPredicate+" ORDER BY id LIMIT ?", recurrenceNone, recurrenceBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if err := attachTags(ctx, db(), due); err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, task := range due {
+		occurrence, ok, err := createNextOccurrence(ctx, task, now)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+			publishTaskEvent(eventTaskCreated, occurrence)
+		}
+	}
+	return created, nil
+}
+
+func createNextOccurrence(ctx context.Context, task Task, now time.Time) (Task, bool, error) {
+	step, ok := recurrenceSteps[task.Recurrence]
+	if !ok {
+		return Task{}, false, nil
+	}
+
+	defer holdWrites()()
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		return Task{}, false, err
+	}
+	defer tx.Rollback()
+
+	occurrence := Task{
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      "pending",
+		DueDate:     nextDueDate(task.DueDate, step, now),
+		Priority:    task.Priority,
+		Assignee:    task.Assignee,
+		ParentID:    task.ParentID,
+		Recurrence:  task.Recurrence,
+		Tags:        task.Tags,
+	}
+	owner := ""
+	if task.OwnerID != nil {
+		owner = *task.OwnerID
+	}
+	if err := insertTask(ctx, tx, &occurrence, owner); err != nil {
+		return Task{}, false, err
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE tasks SET next_occurrence_id = ? WHERE id = ? AND next_occurrence_id IS NULL", occurrence.ID, task.ID)
+	if err != nil {
+		return Task{}, false, err
+	}
+	if claimed, _ := result.RowsAffected(); claimed == 0 {
+		// Another instance got there first.
+		return Task{}, false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Task{}, false, err
+	}
+	return occurrence, true, nil
+}
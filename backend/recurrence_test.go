@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDueDate(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	due := func(s string) *string { return &s }
+
+	assert.Equal(t, "2024-03-12T09:00:00Z", *nextDueDate(due("2024-03-11T09:00:00Z"), recurrenceSteps["daily"], now))
+	assert.Equal(t, "2024-03-13T09:00:00Z", *nextDueDate(due("2024-02-28T09:00:00Z"), recurrenceSteps["weekly"], now), "a late task skips to the next future date")
+	assert.Equal(t, "2024-04-10T12:00:00Z", *nextDueDate(nil, recurrenceSteps["monthly"], now))
+}
+
+func TestGenerateRecurrences(t *testing.T) {
+	router := setupTestRouter()
+	due := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	done := createTestTask(t, router, Task{Title: "Standup notes", Status: "completed", Priority: "high", Recurrence: "Daily", DueDate: &due})
+	assert.Equal(t, "daily", done.Recurrence)
+	createTestTask(t, router, Task{Title: "Weekly report", Recurrence: "weekly"})
+	once := createTestTask(t, router, Task{Title: "One-off", Status: "completed"})
+	assert.Equal(t, recurrenceNone, once.Recurrence)
+
+	created, err := generateRecurrences(context.Background(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created, "only completed recurring tasks spawn an occurrence")
+
+	tasks := listTestTasks(t, router, "?q=Standup&status=pending")
+	if assert.Len(t, tasks, 1) {
+		next := tasks[0]
+		assert.Equal(t, "daily", next.Recurrence)
+		assert.Equal(t, "high", next.Priority)
+		dueAt, _ := time.Parse(time.RFC3339, due)
+		assert.Equal(t, dueAt.AddDate(0, 0, 1).Format(time.RFC3339), *next.DueDate)
+	}
+
+	created, err = generateRecurrences(context.Background(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, created, "a task only ever spawns one occurrence")
+}
+
+func TestRecurrenceValidation(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewBufferString(`{"title":"Sometimes","recurrence":"hourly"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "recurrence must be one of")
+}
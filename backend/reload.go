@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+)
+
+// reloadMu serializes reloads so two can't each start from the same config
+// and drop the other's changes.
+var reloadMu sync.Mutex
+
+// reloadableSetting is one group of settings that is safe to change at
+// runtime, by name for the reload log.
+type reloadableSetting struct {
+	name  string
+	get   func(*Config) interface{}
+	apply func(dst, src *Config)
+}
+
+var reloadableSettings = []reloadableSetting{
+	{"logging.level", func(c *Config) interface{} { return c.Logging.Level }, func(d, s *Config) { d.Logging.Level = s.Logging.Level }},
+	{"logging.format", func(c *Config) interface{} { return c.Logging.Format }, func(d, s *Config) { d.Logging.Format = s.Logging.Format }},
+	{"logging.skip_paths", func(c *Config) interface{} { return c.Logging.SkipPaths }, func(d, s *Config) { d.Logging.SkipPaths = s.Logging.SkipPaths }},
+	{"security.cors_enabled", func(c *Config) interface{} { return c.Security.CorsEnabled }, func(d, s *Config) { d.Security.CorsEnabled = s.Security.CorsEnabled }},
+	{"security.cors_origins", func(c *Config) interface{} { return c.Security.CorsOrigins }, func(d, s *Config) { d.Security.CorsOrigins = s.Security.CorsOrigins }},
+	{"security.rate_limit", func(c *Config) interface{} { return c.Security.RateLimit }, func(d, s *Config) { d.Security.RateLimit = s.Security.RateLimit }},
+}
+
+// reloadConfig re-reads the config file and environment and applies the
+// settings in reloadableSettings. Nothing is applied unless the new config is
+// valid. It returns the names of the settings that changed, and whether other
+// changes were found that only take effect after a restart.
+func reloadConfig(path string) (changed []string, needsRestart bool, err error) {
+	next, err := readConfig(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := applyEnvOverrides(&next, os.LookupEnv); err != nil {
+		return nil, false, err
+	}
+	if err := validateConfig(next); err != nil {
+		return nil, false, err
+	}
+
+	reloadMu.Lock()
+	current := *config()
+	for _, setting := range reloadableSettings {
+		if !reflect.DeepEqual(setting.get(&current), setting.get(&next)) {
+			changed = append(changed, setting.name)
+			setting.apply(&current, &next)
+		}
+	}
+	// With the reloadable settings copied over, any remaining difference is
+	// in a setting that needs a restart.
+	needsRestart = !reflect.DeepEqual(current, next)
+	setConfig(current)
+	reloadMu.Unlock()
+
+	if err := setupLogging(); err != nil {
+		return changed, needsRestart, err
+	}
+	return changed, needsRestart, nil
+}
+
+// startConfigReload reloads the config from path on every SIGHUP until ctx is
+// cancelled.
+func startConfigReload(ctx context.Context, path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				changed, needsRestart, err := reloadConfig(path)
+				if err != nil {
+					logger.Error("config reload failed, keeping the current settings", "path", path, "error", err)
+					continue
+				}
+				logger.Info("config reloaded", "path", path, "changed", changed)
+				if needsRestart {
+					logger.Warn("config has changes that only apply after a restart", "path", path)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const reloadTestConfig = `
+app:
+  port: 8080
+database:
+  type: sqlite
+  path: %s
+logging:
+  level: %s
+  format: text
+security:
+  cors_enabled: true
+  cors_origins: [%s]
+`
+
+func writeReloadTestConfig(t *testing.T, path, dbPath, level, origins string) {
+	t.Helper()
+	content := []byte(fmt.Sprintf(reloadTestConfig, dbPath, level, origins))
+	assert.NoError(t, os.WriteFile(path, content, 0o600))
+}
+
+func TestReloadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeReloadTestConfig(t, path, "./a.db", "info", "https://a.example")
+	cfg, err := readConfig(path)
+	assert.NoError(t, err)
+	setConfig(cfg)
+	assert.NoError(t, setupLogging())
+	t.Cleanup(func() {
+		setupTestRouter()
+		setupLogging()
+	})
+
+	writeReloadTestConfig(t, path, "./b.db", "debug", "https://b.example")
+
+	// Handlers keep reading the reloadable settings during the reload.
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = config().Security.CorsOrigins
+				_ = config().Security.RateLimit
+				logger.Debug("reading during reload")
+			}
+		}
+	}()
+
+	changed, needsRestart, err := reloadConfig(path)
+	close(stop)
+	wg.Wait()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"logging.level", "security.cors_origins"}, changed)
+	assert.True(t, needsRestart, "database.path can't change at runtime")
+	assert.Equal(t, "./a.db", config().Database.Path)
+	assert.Equal(t, []string{"https://b.example"}, config().Security.CorsOrigins)
+	assert.True(t, logger.Enabled(context.Background(), slog.LevelDebug), "the new level applies to the existing logger")
+}
+
+func TestReloadConfigInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeReloadTestConfig(t, path, "./a.db", "info", "https://a.example")
+	cfg, err := readConfig(path)
+	assert.NoError(t, err)
+	setConfig(cfg)
+	t.Cleanup(func() {
+		setupTestRouter()
+		setupLogging()
+	})
+
+	writeReloadTestConfig(t, path, "./a.db", "chatty", "https://b.example")
+	_, _, err = reloadConfig(path)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"https://a.example"}, config().Security.CorsOrigins, "nothing is applied from an invalid config")
+}
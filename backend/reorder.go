@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReorderRequest is the body of POST /tasks/reorder. With a status, the
+// order is that of the status column alone, as on a kanban board, and
+// leaves the global order and every other column's alone.
+type ReorderRequest struct {
+	IDs    []int  `json:"ids"`
+	Status string `json:"status"`
+}
+
+// statusPositionScope is the task_positions scope holding the order of one
+// status column.
+func statusPositionScope(status string) string {
+	return "status:" + status
+}
+
+// taskPositionColumn returns the expression sort=position orders by: the
+// position within the status column when the list is filtered to one valid
+// status, reporting true, and the global position otherwise. The scope is
+// one of validStatuses, so it is safe to inline.
+func taskPositionColumn(c *gin.Context) (string, bool) {
+	status := normalizeStatus(c.Query("status"))
+	if !validStatuses[status] {
+		return "position", false
+	}
+	return "(SELECT task_positions.position FROM task_positions WHERE task_positions.task_id = tasks.id AND task_positions.scope = '" + statusPositionScope(status) + "')", true
+}
+
+// reorderTasks places the listed tasks first in the custom order, in the
+// order given, and renumbers every other placed task after them so they keep
+// their relative order. Unknown or deleted ids are skipped, like the bulk
+// endpoints, and tasks never placed keep a null position.
+//
+// With a status, only tasks in that status are placed, and the positions are
+// stored for that column in task_positions, which GET /tasks?status=...
+// &sort=position reads. Without one the global tasks.position is set.
+//
+// Positions are presentation only: reordering doesn't bump a task's version,
+// so it never causes a conflict for someone editing the task.
+func reorderTasks(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := validateBulkIDs(req.IDs); err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+	seen := make(map[int]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if seen[id] {
+			respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, newValidationError(msgIDsDuplicate, id)))
+			return
+		}
+		seen[id] = true
+	}
+
+	filter, filterArgs := ownerScope(c)
+	filter = notDeletedPredicate + filter
+	var store positionStore = globalPositions{}
+	if req.Status != "" {
+		status := normalizeStatus(req.Status)
+		if !validStatuses[status] {
+			respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, newValidationError(msgStatusInvalid)))
+			return
+		}
+		filter += " AND status = ?"
+		filterArgs = append(filterArgs, status)
+		store = scopedPositions{scope: statusPositionScope(status)}
+	}
+
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer tx.Rollback()
+
+	rest, err := store.placedExcept(ctx, tx, req.IDs, filter, filterArgs)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	var reordered []Task
+	position := 0
+	for _, id := range req.IDs {
+		tasks, err := queryTasks(ctx, tx, "SELECT "+taskColumns+" FROM tasks WHERE id = ? AND "+filter, append([]interface{}{id}, filterArgs...)...)
+		if err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		if len(tasks) == 0 {
+			continue
+		}
+		position++
+		if err := store.set(ctx, tx, id, position); err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		reordered = append(reordered, tasks[0])
+	}
+	for _, id := range rest {
+		position++
+		if err := store.set(ctx, tx, id, position); err != nil {
+			respondInternalError(c, err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	for _, task := range reordered {
+		publishTaskEvent(eventTaskUpdated, task)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reordered": len(reordered)})
+}
+
+// positionStore reads and writes one custom order.
+type positionStore interface {
+	// placedExcept returns the tasks matching filter that already have a
+	// position, other than ids, in their current order.
+	placedExcept(ctx context.Context, q dbtx, ids []int, filter string, filterArgs []interface{}) ([]int, error)
+	set(ctx context.Context, q dbtx, id, position int) error
+}
+
+// globalPositions is the order kept in tasks.position.
+type globalPositions struct{}
+
+func (globalPositions) placedExcept(ctx context.Context, q dbtx, ids []int, filter string, filterArgs []interface{}) ([]int, error) {
+	in, args := inClause(ids)
+	return queryIDs(ctx, q, "SELECT id FROM tasks WHERE position IS NOT NULL AND id NOT "+in+" AND "+filter+" ORDER BY position, id", append(args, filterArgs...)...)
+}
+
+func (globalPositions) set(ctx context.Context, q dbtx, id, position int) error {
+	_, err := q.ExecContext(ctx, "UPDATE tasks SET position = ? WHERE id = ?", position, id)
+	return err
+}
+
+// scopedPositions is an order kept in task_positions under scope.
+type scopedPositions struct {
+	scope string
+}
+
+func (s scopedPositions) placedExcept(ctx context.Context, q dbtx, ids []int, filter string, filterArgs []interface{}) ([]int, error) {
+	in, args := inClause(ids)
+	args = append([]interface{}{s.scope}, args...)
+	return queryIDs(ctx, q, "SELECT id FROM tasks JOIN task_positions ON task_positions.task_id = tasks.id WHERE task_positions.scope = ? AND id NOT "+in+" AND "+filter+" ORDER BY task_positions.position, id", append(args, filterArgs...)...)
+}
+
+func (s scopedPositions) set(ctx context.Context, q dbtx, id, position int) error {
+	_, err := q.ExecContext(ctx, "INSERT INTO task_positions (scope, task_id, position) VALUES (?, ?, ?) ON CONFLICT (scope, task_id) DO UPDATE SET position = excluded.position", s.scope, id, position)
+	return err
+}
+
+// queryIDs runs a query returning a single id column.
+func queryIDs(ctx context.Context, q dbtx, query string, args ...interface{}) ([]int, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func reorderTestTasks(t *testing.T, router *gin.Engine, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/reorder", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func positionOrder(t *testing.T, router *gin.Engine, ids ...int) []int {
+	t.Helper()
+
+	wanted := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	var order []int
+	for _, task := range listTestTasks(t, router, "?sort=position") {
+		if wanted[task.ID] {
+			order = append(order, task.ID)
+		}
+	}
+	return order
+}
+
+func TestReorderTasks(t *testing.T) {
+	router := setupTestRouter()
+	a := createTestTask(t, router, Task{Title: "A"})
+	b := createTestTask(t, router, Task{Title: "B"})
+	c := createTestTask(t, router, Task{Title: "C"})
+	d := createTestTask(t, router, Task{Title: "D"})
+	assert.Nil(t, a.Position)
+
+	// Unknown ids are skipped; unplaced tasks sort last, oldest first.
+	w := reorderTestTasks(t, router, fmt.Sprintf(`{"ids":[%d,%d,%d,99999]}`, c.ID, a.ID, b.ID))
+	assert.Equal(t, 200, w.Code, w.Body.String())
+	var response map[string]int
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 3, response["reordered"])
+	assert.Equal(t, []int{c.ID, a.ID, b.ID, d.ID}, positionOrder(t, router, a.ID, b.ID, c.ID, d.ID))
+
+	// Placed tasks left out of the payload keep their order after the listed ones.
+	w = reorderTestTasks(t, router, fmt.Sprintf(`{"ids":[%d,%d]}`, d.ID, b.ID))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, []int{d.ID, b.ID, c.ID, a.ID}, positionOrder(t, router, a.ID, b.ID, c.ID, d.ID))
+
+	task := getTestTask(t, router, a.ID)
+	if assert.NotNil(t, task.Position) {
+		assert.Equal(t, 4, *task.Position)
+	}
+	assert.Equal(t, 1, task.Version, "reordering doesn't bump the version")
+}
+
+func TestReorderTasksValidation(t *testing.T) {
+	router := setupTestRouter()
+	task := createTestTask(t, router, Task{Title: "Only"})
+
+	for _, body := range []string{`{"ids":[]}`, fmt.Sprintf(`{"ids":[%d,%d]}`, task.ID, task.ID)} {
+		w := reorderTestTasks(t, router, body)
+		assert.Equal(t, 400, w.Code, body)
+		assert.Contains(t, w.Body.String(), errCodeValidation, body)
+	}
+}
+
+func TestReorderTasksWithinStatus(t *testing.T) {
+	router := setupTestRouter()
+	var pending, started []int
+	for i := 0; i < 3; i++ {
+		pending = append(pending, createTestTask(t, router, Task{Title: fmt.Sprintf("Todo %d", i)}).ID)
+		started = append(started, createTestTask(t, router, Task{Title: fmt.Sprintf("Doing %d", i), Status: "in_progress"}).ID)
+	}
+	ours := make(map[int]bool)
+	for _, id := range append(pending, started...) {
+		ours[id] = true
+	}
+	columnOrder := func(status string) []int {
+		var order []int
+		for _, task := range listTestTasks(t, router, "?status="+status+"&sort=position") {
+			if ours[task.ID] {
+				order = append(order, task.ID)
+			}
+		}
+		return order
+	}
+
+	w := reorderTestTasks(t, router, fmt.Sprintf(`{"ids":[%d,%d,%d],"status":"pending"}`, pending[2], pending[0], pending[1]))
+	assert.Equal(t, 200, w.Code, w.Body.String())
+	assert.Equal(t, []int{pending[2], pending[0], pending[1]}, columnOrder("pending"))
+
+	// Reordering another column, or the global order, leaves this one alone.
+	// Tasks outside the column are skipped.
+	w = reorderTestTasks(t, router, fmt.Sprintf(`{"ids":[%d,%d,%d],"status":"In Progress"}`, started[1], pending[0], started[2]))
+	assert.Equal(t, 200, w.Code, w.Body.String())
+	var response map[string]int
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 2, response["reordered"])
+	assert.Equal(t, []int{started[1], started[2], started[0]}, columnOrder("in_progress"))
+
+	w = reorderTestTasks(t, router, fmt.Sprintf(`{"ids":[%d,%d]}`, pending[1], started[0]))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, []int{pending[2], pending[0], pending[1]}, columnOrder("pending"))
+	assert.Equal(t, []int{pending[1], started[0]}, positionOrder(t, router, append(pending, started...)...)[:2])
+
+	// The column view reports positions within the column.
+	tasks := listTestTasks(t, router, "?status=pending&sort=position")
+	if assert.NotEmpty(t, tasks) && assert.NotNil(t, tasks[0].Position) {
+		assert.Equal(t, 1, *tasks[0].Position)
+	}
+
+	w = reorderTestTasks(t, router, fmt.Sprintf(`{"ids":[%d],"status":"someday"}`, pending[0]))
+	assert.Equal(t, 400, w.Code)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// expectedSchema lists the tables and columns this build of the code reads
+// and writes. Keep it in step with the migrations.
+var expectedSchema = map[string][]string{
+	"schema_migrations":  {"version", "name", "applied_at"},
+	"comments":           {"id", "task_id", "body", "author", "created_at"},
+	"task_dependencies":  {"task_id", "depends_on_id", "created_at"},
+	"tags":               {"id", "name"},
+	"idempotency_keys":   {"idempotency_key", "owner", "request_hash", "task_id", "response", "created_at"},
+	"task_tags":          {"task_id", "tag_id"},
+	"task_positions":     {"scope", "task_id", "position"},
+	"tasks":              {"id", "title", "description", "status", "created_at", "due_date", "priority", "deleted_at", "created_by", "updated_by", "owner_id", "updated_at", "version", "parent_id", "recurrence", "next_occurrence_id", "completed_at", "archived", "position", "assignee"},
+	"users":              {"id", "username", "password_hash", "created_at"},
+	"webhook_deliveries": {"id", "url", "event", "body", "attempts", "next_attempt_at", "last_error", "failed_at", "created_at"},
+}
+
+// SchemaReport describes how the live database differs from expectedSchema.
+// Missing entries mean the database is behind the code; unexpected columns
+// mean it is ahead.
+type SchemaReport struct {
+	OK bool `json:"ok"`
+	// SchemaVersion is the last applied migration; LatestVersion is the one
+	// this build expects.
+	SchemaVersion     int                 `json:"schema_version"`
+	LatestVersion     int                 `json:"latest_version"`
+	Tables            map[string][]string `json:"tables"`
+	MissingTables     []string            `json:"missing_tables,omitempty"`
+	MissingColumns    map[string][]string `json:"missing_columns,omitempty"`
+	UnexpectedColumns map[string][]string `json:"unexpected_columns,omitempty"`
+}
+
+// tableColumns returns the columns of a table, or nil if it doesn't exist.
+func tableColumns(ctx context.Context, table string) ([]string, error) {
+	if isPostgres() {
+		return postgresTableColumns(ctx, table)
+	}
+
+	rows, err := db().QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%q)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue interface{}
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+func postgresTableColumns(ctx context.Context, table string) ([]string, error) {
+	rows, err := db().QueryContext(ctx, "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? ORDER BY ordinal_position", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+// checkSchema compares the live database against expectedSchema.
+func checkSchema(ctx context.Context) (SchemaReport, error) {
+	report := SchemaReport{
+		OK:                true,
+		Tables:            map[string][]string{},
+		MissingColumns:    map[string][]string{},
+		UnexpectedColumns: map[string][]string{},
+	}
+
+	for table, want := range expectedSchema {
+		have, err := tableColumns(ctx, table)
+		if err != nil {
+			return report, err
+		}
+		if have == nil {
+			report.MissingTables = append(report.MissingTables, table)
+			report.OK = false
+			continue
+		}
+		report.Tables[table] = have
+
+		present := map[string]bool{}
+		for _, column := range have {
+			present[column] = true
+		}
+		expected := map[string]bool{}
+		for _, column := range want {
+			expected[column] = true
+			if !present[column] {
+				report.MissingColumns[table] = append(report.MissingColumns[table], column)
+				report.OK = false
+			}
+		}
+		for _, column := range have {
+			if !expected[column] {
+				report.UnexpectedColumns[table] = append(report.UnexpectedColumns[table], column)
+				report.OK = false
+			}
+		}
+	}
+
+	sort.Strings(report.MissingTables)
+
+	report.LatestVersion = latestSchemaVersion()
+	if report.Tables["schema_migrations"] != nil {
+		version, err := schemaVersion(ctx, db())
+		if err != nil {
+			return report, err
+		}
+		report.SchemaVersion = version
+	}
+	if report.SchemaVersion != report.LatestVersion {
+		report.OK = false
+	}
+	return report, nil
+}
+
+// getSchemaReport reports whether the database schema matches what the code
+// expects, returning 500 with the differences when it doesn't.
+func getSchemaReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	report, err := checkSchema(ctx)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !report.OK {
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, report)
+}
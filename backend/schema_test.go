@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaReportMatches(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/debug/schema", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code, w.Body.String())
+	var report SchemaReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.True(t, report.OK)
+	assert.ElementsMatch(t, expectedSchema["tasks"], report.Tables["tasks"])
+	assert.Equal(t, latestSchemaVersion(), report.SchemaVersion)
+	assert.Equal(t, latestSchemaVersion(), report.LatestVersion)
+}
+
+func TestSchemaReportDetectsPendingMigrations(t *testing.T) {
+	router := setupTestRouter()
+
+	_, err := db().Exec("DELETE FROM schema_migrations WHERE version = ?", latestSchemaVersion())
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/debug/schema", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 500, w.Code)
+	var report SchemaReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, latestSchemaVersion()-1, report.SchemaVersion)
+}
+
+func TestSchemaReportDetectsDrift(t *testing.T) {
+	router := setupTestRouter()
+
+	_, err := db().Exec("DROP TABLE users")
+	assert.NoError(t, err)
+	_, err = db().Exec("ALTER TABLE tasks ADD COLUMN from_the_future TEXT")
+	assert.NoError(t, err)
+	_, err = db().Exec("ALTER TABLE tasks DROP COLUMN updated_by")
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/debug/schema", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 500, w.Code)
+	var report SchemaReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.False(t, report.OK)
+	assert.Equal(t, []string{"users"}, report.MissingTables)
+	assert.Equal(t, []string{"updated_by"}, report.MissingColumns["tasks"])
+	assert.Equal(t, []string{"from_the_future"}, report.UnexpectedColumns["tasks"])
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
+// ftsAvailable records whether the tasks_fts full-text index could be set
+// up. It needs SQLite built with FTS5 (the sqlite_fts5 build tag); PostgreSQL
+// and plain SQLite builds fall back to the ?q= LIKE filter on the task list.
+var ftsAvailable bool
+
+// ftsStatements mirror title and description into an external-content FTS5
+// table and keep it in sync with triggers, so no handler has to remember to.
+var ftsStatements = []string{
+	`CREATE VIRTUAL TABLE tasks_fts USING fts5(title, description, content='tasks', content_rowid='id')`,
+	`CREATE TRIGGER tasks_fts_ai AFTER INSERT ON tasks BEGIN
+		INSERT INTO tasks_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	`CREATE TRIGGER tasks_fts_ad AFTER DELETE ON tasks BEGIN
+		INSERT INTO tasks_fts(tasks_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+	END`,
+	`CREATE TRIGGER tasks_fts_au AFTER UPDATE OF title, description ON tasks BEGIN
+		INSERT INTO tasks_fts(tasks_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+		INSERT INTO tasks_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	`INSERT INTO tasks_fts(tasks_fts) VALUES ('rebuild')`,
+}
+
+// setupFullTextSearch creates the FTS5 index on first start and indexes the
+// existing tasks. When FTS5 isn't compiled in, search is disabled with a
+// warning instead of failing startup.
+func setupFullTextSearch() error {
+	ftsAvailable = false
+	if isPostgres() {
+		return nil
+	}
+
+	var exists int
+	err := db().QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'tasks_fts'").Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		ftsAvailable = true
+		return nil
+	}
+
+	tx, err := db().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range ftsStatements {
+		if _, err := tx.Exec(stmt); err != nil {
+			if strings.Contains(err.Error(), "no such module: fts5") {
+				logger.Warn("full-text search disabled: SQLite was built without FTS5 (build with -tags sqlite_fts5)")
+				return nil
+			}
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	ftsAvailable = true
+	return nil
+}
+
+// ftsQuery turns free text into an FTS5 query that matches every word, each
+// quoted so characters like - or * in user input aren't read as syntax.
+func ftsQuery(q string) string {
+	var terms []string
+	for _, word := range strings.Fields(q) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// searchTasks returns tasks matching every word of q, best match first.
+func searchTasks(c *gin.Context) {
+	ctx := c.Request.Context()
+	if !ftsAvailable {
+		respondError(c, http.StatusNotImplemented, errCodeNotImplemented, "full-text search is not available on this server; use GET /tasks?q= instead")
+		return
+	}
+
+	match := ftsQuery(c.Query("q"))
+	if match == "" {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "q is required")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxSearchLimit {
+			respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "limit must be between 1 and "+strconv.Itoa(maxSearchLimit))
+			return
+		}
+		limit = n
+	}
+
+	scope, scopeArgs := ownerScope(c)
+	query := "SELECT " + taskColumns + " FROM tasks" +
+		" JOIN (SELECT rowid AS fts_id, rank FROM tasks_fts WHERE tasks_fts MATCH ?) m ON m.fts_id = tasks.id" +
+		" WHERE " + notDeletedPredicate + scope + " ORDER BY m.rank LIMIT ?"
+	args := append(append([]interface{}{match}, scopeArgs...), limit)
+
+	rows, err := db().QueryContext(ctx, query, args...)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer rows.Close()
+
+	tasks := []Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	respondTasks(c, http.StatusOK, tasks)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchTestTasks(t *testing.T, q string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	router := setupRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/search?q="+url.QueryEscape(q), nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestFTSQuery(t *testing.T) {
+	assert.Equal(t, `"deploy" "prod-db"`, ftsQuery("  deploy prod-db "))
+	assert.Equal(t, `"say" """hi"""`, ftsQuery(`say "hi"`))
+	assert.Equal(t, "", ftsQuery("   "))
+}
+
+func TestSearchTasksFTS(t *testing.T) {
+	router := setupTestRouter()
+	if !ftsAvailable {
+		t.Skip("SQLite built without FTS5; run with -tags sqlite_fts5")
+	}
+
+	createTestTask(t, router, Task{Title: "Rotate database credentials", Description: "Quarterly rotation"})
+	deploy := createTestTask(t, router, Task{Title: "Write runbook", Description: "Database failover and database restore"})
+	gone := createTestTask(t, router, Task{Title: "Old database task"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/tasks/"+strconv.Itoa(gone.ID), nil)
+	router.ServeHTTP(w, req)
+
+	w = searchTestTasks(t, "database")
+	assert.Equal(t, 200, w.Code)
+	var tasks []Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	assert.Len(t, tasks, 2, "soft-deleted tasks are excluded")
+
+	// Updates are reindexed by the triggers.
+	_, err := db().Exec("UPDATE tasks SET description = 'nothing relevant' WHERE id = ?", deploy.ID)
+	assert.NoError(t, err)
+	w = searchTestTasks(t, "failover")
+	assert.Equal(t, "[]", w.Body.String())
+
+	// Seed rows present before the index was created are searchable too.
+	w = searchTestTasks(t, "production")
+	assert.Contains(t, w.Body.String(), "Deploy to Production")
+}
+
+func TestSearchTasksWithoutFTS(t *testing.T) {
+	setupTestRouter()
+	if ftsAvailable {
+		t.Skip("SQLite built with FTS5")
+	}
+
+	w := searchTestTasks(t, "anything")
+	assert.Equal(t, 501, w.Code)
+	var body APIError
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, errCodeNotImplemented, body.Code)
+}
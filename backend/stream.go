@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// sseKeepAliveInterval keeps proxies from closing an idle event stream.
+	sseKeepAliveInterval = 15 * time.Second
+
+	streamWriteTimeout = 10 * time.Second
+	streamPongTimeout  = 60 * time.Second
+	streamPingInterval = streamPongTimeout * 9 / 10
+)
+
+var upgrader = websocket.Upgrader{CheckOrigin: websocketOriginAllowed}
+
+// websocketOriginAllowed applies the CORS origin allowlist to WebSocket
+// handshakes, which browsers send cross-origin without a preflight.
+// Non-browser clients that send no Origin are allowed.
+func websocketOriginAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range config().Security.CorsOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	// Same-origin pages are always allowed, as with the default upgrader.
+	return strings.EqualFold(origin, "http://"+r.Host) || strings.EqualFold(origin, "https://"+r.Host)
+}
+
+// streamTasks upgrades to a WebSocket and sends every task event as a JSON
+// TaskEvent message until the client disconnects. A client that can't keep up
+// is disconnected with a "try again later" close frame.
+func streamTasks(c *gin.Context) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already answered with an HTTP error.
+		return
+	}
+	defer conn.Close()
+
+	sub := taskEvents.subscribe(currentUser(c))
+	defer taskEvents.unsubscribe(sub)
+
+	// The client never sends data, but reading is what notices it has gone
+	// away and what processes pongs.
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(streamPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongTimeout))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-sub.events:
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client too slow"))
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// streamTaskEvents is the Server-Sent Events counterpart of streamTasks: each
+// task event is sent as an SSE event named after it, with the TaskEvent as
+// JSON data. A comment line every sseKeepAliveInterval keeps idle
+// connections open. The stream ends when the client goes away or, for a
+// client too slow to keep up, when the hub drops it.
+func streamTaskEvents(c *gin.Context) {
+	sub := taskEvents.subscribe(currentUser(c))
+	defer taskEvents.unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Stop nginx and similar proxies from buffering the stream.
+	c.Header("X-Accel-Buffering", "no")
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	// Send the headers straight away so clients see the stream open.
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Event, event)
+			return true
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			return true
+		}
+	})
+}
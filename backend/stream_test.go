@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// newStreamTestServer serves router over a real connection, which streaming
+// needs, and waits for hijacked and streaming handlers to return before the
+// test ends so they don't outlive it.
+func newStreamTestServer(t *testing.T, router http.Handler) *httptest.Server {
+	t.Helper()
+
+	var handlers sync.WaitGroup
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.Add(1)
+		defer handlers.Done()
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(func() {
+		server.Close()
+		handlers.Wait()
+	})
+	return server
+}
+
+func TestStreamTasksWebSocket(t *testing.T) {
+	router := setupTestRouter()
+	server := newStreamTestServer(t, router)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/tasks/stream"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// Wait for the handler to subscribe before publishing.
+	assert.Eventually(t, func() bool {
+		taskEvents.mu.Lock()
+		defer taskEvents.mu.Unlock()
+		return len(taskEvents.subscribers) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	created := createTestTask(t, router, Task{Title: "Pushed"})
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var event TaskEvent
+	assert.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, eventTaskCreated, event.Event)
+	assert.Equal(t, created.ID, event.Task.ID)
+
+	// Disconnecting unsubscribes.
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		taskEvents.mu.Lock()
+		defer taskEvents.mu.Unlock()
+		return len(taskEvents.subscribers) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestStreamTasksRejectsForeignOrigin(t *testing.T) {
+	router := setupTestRouter()
+	config().Security.CorsOrigins = []string{"http://allowed.example"}
+	server := newStreamTestServer(t, router)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/tasks/stream"
+	_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"http://evil.example"}})
+	assert.Error(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
+}
+
+func TestEventHubDropsSlowSubscribers(t *testing.T) {
+	hub := newEventHub()
+	slow := hub.subscribe("")
+	mine := hub.subscribe("alice")
+
+	bob := "bob"
+	for i := 0; i <= subscriberBuffer; i++ {
+		hub.publish(TaskEvent{Event: eventTaskUpdated, Task: Task{ID: i, OwnerID: &bob}})
+	}
+
+	// The slow subscriber got a full buffer and was then dropped.
+	received := 0
+	for range slow.events {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+
+	// Subscribers only see their own tasks and alice saw none of bob's.
+	assert.Len(t, mine.events, 0)
+	_, stillSubscribed := hub.subscribers[mine]
+	assert.True(t, stillSubscribed)
+
+	hub.unsubscribe(slow)
+	hub.unsubscribe(mine)
+	hub.unsubscribe(mine)
+	assert.Empty(t, hub.subscribers)
+}
+
+func TestStreamTaskEventsSSE(t *testing.T) {
+	router := setupTestRouter()
+	server := newStreamTestServer(t, router)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/v1/tasks/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"))
+
+	created := createTestTask(t, router, Task{Title: "Streamed"})
+
+	reader := bufio.NewReader(resp.Body)
+	readLine := func() string {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		return strings.TrimRight(line, "\n")
+	}
+	assert.Equal(t, "event:"+eventTaskCreated, readLine())
+	data, ok := strings.CutPrefix(readLine(), "data:")
+	if assert.True(t, ok) {
+		var event TaskEvent
+		assert.NoError(t, json.Unmarshal([]byte(data), &event))
+		assert.Equal(t, created.ID, event.Task.ID)
+	}
+
+	// Closing the request ends the stream and unsubscribes.
+	cancel()
+	assert.Eventually(t, func() bool {
+		taskEvents.mu.Lock()
+		defer taskEvents.mu.Unlock()
+		return len(taskEvents.subscribers) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
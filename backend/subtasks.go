@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkParent validates a task's parent_id: the parent must be a live task
+// the caller can see, and for an existing task (id > 0) it must not be the
+// task itself or one of its descendants. Problems with the parent come back
+// as validation errors; anything else is a database failure.
+func checkParent(c *gin.Context, q dbtx, id int, parentID *int) error {
+	ctx := c.Request.Context()
+	if parentID == nil {
+		return nil
+	}
+	if _, err := lookupTask(c, q, *parentID); err != nil {
+		if err == sql.ErrNoRows {
+			return newValidationError(msgParentNotFound)
+		}
+		return err
+	}
+	if id == 0 {
+		return nil
+	}
+
+	// Walk up from the proposed parent; reaching the task means it would
+	// become its own ancestor. UNION stops on rows already seen, so the walk
+	// terminates even if the data somehow holds a cycle.
+	var cycles int
+	err := q.QueryRowContext(ctx, `WITH RECURSIVE ancestors (id, parent_id) AS (
+		SELECT id, parent_id FROM tasks WHERE id = ?
+		UNION
+		SELECT t.id, t.parent_id FROM tasks t JOIN ancestors a ON t.id = a.parent_id
+	) SELECT COUNT(*) FROM ancestors WHERE id = ?`, *parentID, id).Scan(&cycles)
+	if err != nil {
+		return err
+	}
+	if cycles > 0 {
+		return newValidationError(msgParentCycle)
+	}
+	return nil
+}
+
+// respondParentError answers a failed checkParent.
+func respondParentError(c *gin.Context, err error) {
+	if isValidationError(err) {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+	respondInternalError(c, err)
+}
+
+// listSubtasks returns the direct children of a task, oldest first.
+func listSubtasks(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := taskIDParam(c)
+	if !ok {
+		return
+	}
+	if _, err := lookupTask(c, db(), id); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, errCodeNotFound, "Task not found")
+		} else {
+			respondInternalError(c, err)
+		}
+		return
+	}
+
+	scope, args := ownerScope(c)
+	rows, err := db().QueryContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE parent_id = ? AND "+notDeletedPredicate+scope+" ORDER BY id", append([]interface{}{id}, args...)...)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer rows.Close()
+
+	subtasks := []Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		subtasks = append(subtasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	respondTasks(c, http.StatusOK, subtasks)
+}
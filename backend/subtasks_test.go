@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setTestParent(t *testing.T, router *gin.Engine, task Task, parentID int) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body := fmt.Sprintf(`{"title":%q,"parent_id":%d,"version":%d}`, task.Title, parentID, task.Version)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/v1/tasks/"+strconv.Itoa(task.ID), bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSubtasks(t *testing.T) {
+	router := setupTestRouter()
+	parent := createTestTask(t, router, Task{Title: "Launch"})
+	first := createTestTask(t, router, Task{Title: "Write copy", ParentID: &parent.ID})
+	createTestTask(t, router, Task{Title: "Book venue", ParentID: &parent.ID})
+	createTestTask(t, router, Task{Title: "Unrelated"})
+
+	if assert.NotNil(t, first.ParentID) {
+		assert.Equal(t, parent.ID, *first.ParentID)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/"+strconv.Itoa(parent.ID)+"/subtasks", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var subtasks []Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &subtasks))
+	if assert.Len(t, subtasks, 2) {
+		assert.Equal(t, "Write copy", subtasks[0].Title)
+		assert.Equal(t, "Book venue", subtasks[1].Title)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks/"+strconv.Itoa(first.ID)+"/subtasks", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "[]", w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks/99999/subtasks", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestSubtaskParentMustExist(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewBufferString(`{"title":"Orphan","parent_id":99999}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "parent_id must refer to an existing task")
+}
+
+func TestSubtaskCyclesAreRejected(t *testing.T) {
+	router := setupTestRouter()
+	root := createTestTask(t, router, Task{Title: "Root"})
+	child := createTestTask(t, router, Task{Title: "Child", ParentID: &root.ID})
+	grandchild := createTestTask(t, router, Task{Title: "Grandchild", ParentID: &child.ID})
+
+	// A task can't be its own parent, nor the child of its descendants.
+	for _, parentID := range []int{root.ID, child.ID, grandchild.ID} {
+		w := setTestParent(t, router, root, parentID)
+		assert.Equal(t, 400, w.Code, parentID)
+		assert.Contains(t, w.Body.String(), "its own ancestor")
+	}
+
+	// Moving a task elsewhere in the tree is fine.
+	other := createTestTask(t, router, Task{Title: "Other"})
+	assert.Equal(t, 200, setTestParent(t, router, grandchild, other.ID).Code)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxTagLength   = 50
+	maxTagsPerTask = 20
+)
+
+// normalizeTags trims every tag, drops blank ones and duplicates, and sorts
+// the rest so a task's tags always come back in the same order. A nil slice
+// stays nil: on update it means "leave the tags alone".
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(tags))
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, newValidationError(msgTagTooLong, maxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTagsPerTask {
+		return nil, newValidationError(msgTagsTooMany, maxTagsPerTask)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// setTaskTags replaces a task's tags, creating tags that don't exist yet.
+func setTaskTags(ctx context.Context, q dbtx, taskID int, tags []string) error {
+	if _, err := q.ExecContext(ctx, "DELETE FROM task_tags WHERE task_id = ?", taskID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := q.ExecContext(ctx, "INSERT INTO tags (name) VALUES (?) ON CONFLICT (name) DO NOTHING", tag); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, "INSERT INTO task_tags (task_id, tag_id) SELECT ?, id FROM tags WHERE name = ?", taskID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachTags loads the tags of every task in one query per maxBatchSize
+// tasks. Tasks without tags get an empty list.
+func attachTags(ctx context.Context, q dbtx, tasks []Task) error {
+	byID := make(map[int]*Task, len(tasks))
+	ids := make([]int, 0, len(tasks))
+	for i := range tasks {
+		tasks[i].Tags = []string{}
+		byID[tasks[i].ID] = &tasks[i]
+		ids = append(ids, tasks[i].ID)
+	}
+
+	for start := 0; start < len(ids); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		in, args := inClause(ids[start:end])
+		rows, err := q.QueryContext(ctx, "SELECT tt.task_id, t.name FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE tt.task_id "+in+" ORDER BY t.name", args...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var taskID int
+			var name string
+			if err := rows.Scan(&taskID, &name); err != nil {
+				rows.Close()
+				return err
+			}
+			byID[taskID].Tags = append(byID[taskID].Tags, name)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return err
+		}
+		rows.Close()
+	}
+	return nil
+}
+
+// taskTags returns the tags of a single task.
+func taskTags(ctx context.Context, q dbtx, task *Task) error {
+	tasks := []Task{*task}
+	if err := attachTags(ctx, q, tasks); err != nil {
+		return err
+	}
+	task.Tags = tasks[0].Tags
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := normalizeTags([]string{" backend ", "api", "", "backend", "  "})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api", "backend"}, tags)
+
+	tags, err = normalizeTags(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, tags)
+
+	_, err = normalizeTags([]string{strings.Repeat("x", maxTagLength+1)})
+	assert.Error(t, err)
+
+	many := make([]string, maxTagsPerTask+1)
+	for i := range many {
+		many[i] = strconv.Itoa(i)
+	}
+	_, err = normalizeTags(many)
+	assert.Error(t, err)
+}
+
+func TestTaskTags(t *testing.T) {
+	router := setupTestRouter()
+	tagged := createTestTask(t, router, Task{Title: "Tagged", Tags: []string{"backend", " urgent", "backend"}})
+	assert.Equal(t, []string{"backend", "urgent"}, tagged.Tags)
+	other := createTestTask(t, router, Task{Title: "Other", Tags: []string{"frontend"}})
+
+	tasks := listTestTasks(t, router, "?tag=backend")
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, tagged.ID, tasks[0].ID)
+		assert.Equal(t, []string{"backend", "urgent"}, tasks[0].Tags)
+	}
+
+	// Omitting tags on update keeps them; an empty list clears them.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/v1/tasks/"+strconv.Itoa(tagged.ID), bytes.NewBufferString(`{"title":"Tagged","version":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var updated Task
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	assert.Equal(t, []string{"backend", "urgent"}, updated.Tags)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/v1/tasks/"+strconv.Itoa(other.ID), bytes.NewBufferString(`{"title":"Other","tags":["backend"],"version":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Len(t, listTestTasks(t, router, "?tag=backend"), 2)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/v1/tasks/"+strconv.Itoa(tagged.ID), bytes.NewBufferString(`{"title":"Tagged","tags":[],"version":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/tasks/"+strconv.Itoa(tagged.ID), nil)
+	router.ServeHTTP(w, req)
+	var fetched Task
+	json.Unmarshal(w.Body.Bytes(), &fetched)
+	assert.Empty(t, fetched.Tags)
+	assert.Len(t, listTestTasks(t, router, "?tag=backend"), 1)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	// Embedded so ?tz= works in minimal containers without a zoneinfo database.
+	_ "time/tzdata"
+
+	"github.com/gin-gonic/gin"
+)
+
+const timezoneKey = "timezone"
+
+// timestampLayouts are the forms timestamps come back from the database in:
+// the driver's RFC3339 rendering of DATETIME and TIMESTAMPTZ columns, and the
+// raw text SQLite's CURRENT_TIMESTAMP writes into untyped columns. Layouts
+// without a zone are UTC.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// Timestamp is a time.Time that is always written as RFC3339 to the second,
+// in JSON, XML and CSV alike, whatever precision the database kept. It reads
+// any layout parseTimestamp accepts.
+type Timestamp struct {
+	time.Time
+}
+
+func (t Timestamp) String() string {
+	return t.Format(time.RFC3339)
+}
+
+func (t Timestamp) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *Timestamp) UnmarshalText(data []byte) error {
+	parsed, err := parseTimestamp(string(data))
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	return t.UnmarshalText([]byte(raw))
+}
+
+func parseTimestamp(raw string) (time.Time, error) {
+	var err error
+	for _, layout := range timestampLayouts {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, strings.TrimSpace(raw), time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// normalizeTimestamp rewrites a stored timestamp in place as RFC3339 in loc.
+// Values that don't parse are left as they are rather than failing the read.
+func normalizeTimestamp(s *string, loc *time.Location) {
+	if s == nil || *s == "" {
+		return
+	}
+	if t, err := parseTimestamp(*s); err == nil {
+		*s = t.In(loc).Format(time.RFC3339)
+	}
+}
+
+// setScannedTimes finishes reading a task's timestamps: completed_at is
+// nullable so it is scanned on its own, and drivers hand back times in the
+// session's zone, so everything is moved to UTC.
+func setScannedTimes(task *Task, completedAt sql.NullTime) {
+	task.CompletedAt = nil
+	if completedAt.Valid {
+		task.CompletedAt = &Timestamp{completedAt.Time}
+	}
+	normalizeTaskTimes(task, time.UTC)
+}
+
+// normalizeTaskTimes moves every timestamp of task to loc.
+func normalizeTaskTimes(task *Task, loc *time.Location) {
+	task.CreatedAt.Time = task.CreatedAt.In(loc)
+	task.UpdatedAt.Time = task.UpdatedAt.In(loc)
+	if task.CompletedAt != nil {
+		task.CompletedAt.Time = task.CompletedAt.In(loc)
+	}
+	if task.DeletedAt != nil {
+		task.DeletedAt.Time = task.DeletedAt.In(loc)
+	}
+	normalizeTimestamp(task.DueDate, loc)
+}
+
+// timezoneMiddleware validates ?tz=, an IANA zone name such as
+// America/New_York, for requestLocation.
+func timezoneMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name, ok := c.GetQuery("tz")
+		if !ok {
+			c.Next()
+			return
+		}
+		// LoadLocation reads "" as UTC and "Local" as the server's zone;
+		// neither is something a client should be asking for.
+		name = strings.TrimSpace(name)
+		loc, err := time.LoadLocation(name)
+		if err != nil || name == "" || name == "Local" {
+			respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, newValidationError(msgTimezoneInvalid, name)))
+			return
+		}
+		c.Set(timezoneKey, loc)
+		c.Next()
+	}
+}
+
+// requestLocation is the zone read responses render timestamps in: ?tz= when
+// given, otherwise UTC.
+func requestLocation(c *gin.Context) *time.Location {
+	if loc, ok := c.Get(timezoneKey); ok {
+		return loc.(*time.Location)
+	}
+	return time.UTC
+}
+
+// inRequestZone converts the timestamps of tasks, which are read in UTC, to
+// requestLocation in place. Read handlers call it just before encoding.
+func inRequestZone(c *gin.Context, tasks []Task) {
+	if loc := requestLocation(c); loc != time.UTC {
+		for i := range tasks {
+			normalizeTaskTimes(&tasks[i], loc)
+		}
+	}
+}
+
+// taskInRequestZone is inRequestZone for a single task.
+func taskInRequestZone(c *gin.Context, task *Task) {
+	if loc := requestLocation(c); loc != time.UTC {
+		normalizeTaskTimes(task, loc)
+	}
+}
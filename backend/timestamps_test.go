@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2026, 3, 8, 6, 30, 0, 0, time.UTC)
+	for _, raw := range []string{
+		"2026-03-08 06:30:00",
+		"2026-03-08T06:30:00",
+		"2026-03-08T06:30:00Z",
+		"2026-03-08T01:30:00-05:00",
+		"2026-03-08 01:30:00-05:00",
+	} {
+		got, err := parseTimestamp(raw)
+		if assert.NoError(t, err, raw) {
+			assert.True(t, want.Equal(got), raw)
+		}
+	}
+
+	_, err := parseTimestamp("yesterday")
+	assert.Error(t, err)
+}
+
+func TestTimestampEncoding(t *testing.T) {
+	at := Timestamp{time.Date(2026, 3, 8, 6, 30, 0, 123456789, time.UTC)}
+	completed := at
+	task := Task{ID: 1, CreatedAt: at, UpdatedAt: at, CompletedAt: &completed}
+
+	data, err := json.Marshal(task)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"created_at":"2026-03-08T06:30:00Z"`, "fractional seconds are dropped")
+	assert.Contains(t, string(data), `"completed_at":"2026-03-08T06:30:00Z"`)
+
+	data, err = xml.Marshal(task)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "<created_at>2026-03-08T06:30:00Z</created_at>")
+
+	var decoded Task
+	assert.NoError(t, json.Unmarshal([]byte(`{"created_at":"2026-03-08 06:30:00","updated_at":null,"completed_at":null}`), &decoded))
+	assert.True(t, decoded.CreatedAt.Equal(at.Truncate(time.Second)))
+	assert.True(t, decoded.UpdatedAt.IsZero())
+	assert.Nil(t, decoded.CompletedAt)
+	assert.Error(t, json.Unmarshal([]byte(`{"created_at":"soon"}`), &decoded))
+}
+
+func TestTimestampsAcrossDST(t *testing.T) {
+	router := setupTestRouter()
+	task := createTestTask(t, router, Task{Title: "Around the clock change"})
+	path := "/api/v1/tasks/" + strconv.Itoa(task.ID)
+
+	tests := []struct {
+		name    string
+		stored  string
+		utc     string
+		newYork string
+	}{
+		// US clocks sprang forward at 07:00 UTC on 8 March 2026.
+		{"before spring forward", "2026-03-08 06:30:00", "2026-03-08T06:30:00Z", "2026-03-08T01:30:00-05:00"},
+		{"after spring forward", "2026-03-08 07:30:00", "2026-03-08T07:30:00Z", "2026-03-08T03:30:00-04:00"},
+		// They fell back at 06:00 UTC on 1 November 2026, so 01:30 happens twice.
+		{"before fall back", "2026-11-01 05:30:00", "2026-11-01T05:30:00Z", "2026-11-01T01:30:00-04:00"},
+		{"after fall back", "2026-11-01 06:30:00", "2026-11-01T06:30:00Z", "2026-11-01T01:30:00-05:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db().Exec("UPDATE tasks SET created_at = ?, due_date = ? WHERE id = ?", tt.stored, tt.stored, task.ID)
+			assert.NoError(t, err)
+
+			got := getTestTask(t, router, task.ID)
+			assert.Equal(t, tt.utc, got.CreatedAt.String())
+			if assert.NotNil(t, got.DueDate) {
+				assert.Equal(t, tt.utc, *got.DueDate)
+			}
+
+			inNewYork := listTestTasks(t, router, "?tz=America/New_York")
+			for _, listed := range inNewYork {
+				if listed.ID == task.ID {
+					assert.Equal(t, tt.newYork, listed.CreatedAt.String())
+					assert.Equal(t, tt.newYork, *listed.DueDate)
+				}
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", path+"?tz=America/New_York", nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, 200, w.Code)
+			assert.Contains(t, w.Body.String(), `"created_at":"`+tt.newYork+`"`)
+		})
+	}
+}
+
+func TestInvalidTimezone(t *testing.T) {
+	router := setupTestRouter()
+
+	for _, tz := range []string{"Mars/Olympus_Mons", "", "Local"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/tasks?tz="+tz, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code, tz)
+		assert.Contains(t, w.Body.String(), errCodeValidation, tz)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSConfig points at a PEM certificate and private key. When both are set
+// the server terminates HTTPS itself; otherwise it serves plain HTTP, e.g.
+// behind a proxy that handles TLS.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+func (t TLSConfig) enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// validateTLS checks that both files are given, readable and form a usable
+// certificate and key pair, so a bad path fails at startup rather than when
+// the listener starts.
+func validateTLS(t TLSConfig) error {
+	if !t.enabled() {
+		return nil
+	}
+	if t.CertFile == "" || t.KeyFile == "" {
+		return errors.New("security.tls needs both cert_file and key_file")
+	}
+
+	var problems []error
+	for _, file := range []struct{ setting, path string }{{"cert_file", t.CertFile}, {"key_file", t.KeyFile}} {
+		f, err := os.Open(file.path)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("security.tls.%s: %w", file.setting, err))
+			continue
+		}
+		f.Close()
+	}
+	if len(problems) > 0 {
+		return errors.Join(problems...)
+	}
+
+	if _, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile); err != nil {
+		return fmt.Errorf("security.tls: %w", err)
+	}
+	return nil
+}
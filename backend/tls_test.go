@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 and
+// its key to dir.
+func writeTestCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "taskhub test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestValidateTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir)
+
+	assert.NoError(t, validateTLS(TLSConfig{}))
+	assert.NoError(t, validateTLS(TLSConfig{CertFile: certFile, KeyFile: keyFile}))
+	assert.ErrorContains(t, validateTLS(TLSConfig{CertFile: certFile}), "needs both cert_file and key_file")
+	assert.ErrorContains(t, validateTLS(TLSConfig{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.pem")}), "security.tls.key_file")
+	assert.ErrorContains(t, validateTLS(TLSConfig{CertFile: keyFile, KeyFile: keyFile}), "security.tls")
+}
+
+func TestServeTLS(t *testing.T) {
+	setupTestRouter()
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+	config().Security.TLS = TLSConfig{CertFile: certFile, KeyFile: keyFile}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, &http.Server{Addr: addr, Handler: mux}, time.Second) }()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	var resp *http.Response
+	assert.Eventually(t, func() bool {
+		resp, err = client.Get("https://" + addr + "/")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	if resp != nil {
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.NotNil(t, resp.TLS)
+		resp.Body.Close()
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
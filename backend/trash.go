@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trashOrder lists the most recently deleted tasks first.
+const trashOrder = " ORDER BY deleted_at DESC, id DESC"
+
+const defaultTrashRetentionDays = 30
+
+func trashRetention() time.Duration {
+	days := config().Trash.RetentionDays
+	if days <= 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// parseAge reads an age such as 30d, or any Go duration such as 12h. Zero
+// is allowed and means everything.
+func parseAge(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, newValidationError(msgOlderThanInvalid)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(raw)
+	if err != nil || age < 0 {
+		return 0, newValidationError(msgOlderThanInvalid)
+	}
+	return age, nil
+}
+
+// listTrash lists the caller's soft-deleted tasks, most recently deleted
+// first, with their deleted_at, so they can be found and restored. It pages
+// like getTasks: ?cursor= continues after the task with that id in this
+// order, and ?limit=&offset= sets the X-Total-Count headers.
+func listTrash(c *gin.Context) {
+	ctx := c.Request.Context()
+	fields, err := requestedFields(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+
+	scope, args := ownerScope(c)
+	where := " WHERE deleted_at IS NOT NULL" + scope
+	page := ""
+	cursor, limit, paged, err := cursorPage(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+		return
+	}
+	if paged {
+		if cursor > 0 {
+			where += " AND (deleted_at < (SELECT deleted_at FROM tasks WHERE id = ?) OR (deleted_at = (SELECT deleted_at FROM tasks WHERE id = ?) AND id < ?))"
+			args = append(args, cursor, cursor, cursor)
+		}
+		// One extra row tells us whether there is a next page.
+		page = " LIMIT ?"
+		args = append(args, limit+1)
+	} else {
+		limit, offset, ok, err := offsetPage(c)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+			return
+		}
+		if ok {
+			var total int
+			if err := db().QueryRowContext(ctx, "SELECT COUNT(*) FROM tasks"+where, args...).Scan(&total); err != nil {
+				respondInternalError(c, err)
+				return
+			}
+			setPageHeaders(c, total, limit, offset)
+			page = " LIMIT ? OFFSET ?"
+			args = append(args, limit, offset)
+		}
+	}
+
+	rows, err := db().QueryContext(ctx, "SELECT "+taskColumns+", deleted_at FROM tasks"+where+trashOrder+page, args...)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	defer rows.Close()
+
+	tasks := []Task{}
+	for rows.Next() {
+		var deletedAt sql.NullTime
+		task, err := scanTask(rows, &deletedAt)
+		if err != nil {
+			respondInternalError(c, err)
+			return
+		}
+		task.DeletedAt = &Timestamp{deletedAt.Time.UTC()}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	if paged && len(tasks) > limit {
+		tasks = tasks[:limit]
+		c.Header("Link", nextPageLink(c.Request.URL, tasks[limit-1].ID))
+	}
+	if err := attachTags(ctx, db(), tasks); err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	body, contentType, err := encodeTasks(c, tasks, fields)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// purgeTask permanently removes a task that is already in the trash, with
+// its comments, tags and dependencies. Live tasks get 409 so a single call
+// can never destroy something that wasn't deleted first.
+func purgeTask(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := taskIDParam(c)
+	if !ok {
+		return
+	}
+
+	scope, args := ownerScope(c)
+	args = append([]interface{}{id}, args...)
+	result, err := db().ExecContext(ctx, "DELETE FROM tasks WHERE id = ? AND deleted_at IS NOT NULL"+scope, args...)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		var exists int
+		err := db().QueryRowContext(ctx, "SELECT 1 FROM tasks WHERE id = ?"+scope, args...).Scan(&exists)
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, errCodeNotFound, "Task not found")
+		} else if err != nil {
+			respondInternalError(c, err)
+		} else {
+			respondError(c, http.StatusConflict, errCodeConflict, "Task is not deleted")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task purged successfully"})
+}
+
+// purgeTrash permanently removes the caller's tasks that were deleted more
+// than ?older_than= ago, trash.retention_days by default, and returns how
+// many it removed.
+func purgeTrash(c *gin.Context) {
+	age := trashRetention()
+	if raw, ok := c.GetQuery("older_than"); ok {
+		var err error
+		if age, err = parseAge(raw); err != nil {
+			respondError(c, http.StatusBadRequest, errCodeValidation, localize(c, err))
+			return
+		}
+	}
+
+	scope, args := ownerScope(c)
+	purged, err := purgeDeletedBefore(c.Request.Context(), time.Now().Add(-age), scope, args)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+// purgeDeletedBefore permanently removes the tasks in scope that were
+// soft-deleted before cutoff, in one transaction, and returns how many.
+func purgeDeletedBefore(ctx context.Context, cutoff time.Time, scope string, scopeArgs []interface{}) (int64, error) {
+	tx, err := db().BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	args := append([]interface{}{cutoff.UTC().Format(time.RFC3339)}, scopeArgs...)
+	result, err := tx.ExecContext(ctx, "DELETE FROM tasks WHERE deleted_at IS NOT NULL AND "+timestampCompare("deleted_at", "<")+scope, args...)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return purged, tx.Commit()
+}
+
+// startTrashPurge empties the trash of everything past trash.retention_days
+// every trash.purge_interval_hours until ctx is cancelled. A zero interval
+// disables the job.
+func startTrashPurge(ctx context.Context) {
+	hours := config().Trash.PurgeIntervalHours
+	if hours <= 0 {
+		return
+	}
+	go runEvery(ctx, time.Duration(hours)*time.Hour, func() {
+		purged, err := purgeExpiredTrash(ctx)
+		if err != nil {
+			logger.Error("trash purge failed", "error", err)
+			return
+		}
+		if purged > 0 {
+			logger.Info("purged deleted tasks", "count", purged)
+		}
+	})
+}
+
+// purgeExpiredTrash is the scheduled purge of every task deleted longer ago
+// than trash.retention_days. It waits for a running VACUUM first.
+func purgeExpiredTrash(ctx context.Context) (int64, error) {
+	defer holdWrites()()
+	return purgeDeletedBefore(ctx, time.Now().Add(-trashRetention()), "", nil)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func listTestTrash(t *testing.T, router *gin.Engine, query string) ([]Task, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/trash"+query, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code, w.Body.String())
+
+	var tasks []Task
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	return tasks, w
+}
+
+func trashedIDs(tasks []Task) []int {
+	ids := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func TestListTrash(t *testing.T) {
+	router := setupTestRouter()
+	kept := createTestTask(t, router, Task{Title: "Kept"})
+	var deleted []Task
+	for i, at := range []string{"2026-01-02 10:00:00", "2026-01-01 10:00:00", "2026-01-03 10:00:00"} {
+		task := createTestTask(t, router, Task{Title: fmt.Sprintf("Deleted %d", i)})
+		_, err := db().Exec("UPDATE tasks SET deleted_at = ? WHERE id = ?", at, task.ID)
+		assert.NoError(t, err)
+		deleted = append(deleted, task)
+	}
+	newestFirst := []int{deleted[2].ID, deleted[0].ID, deleted[1].ID}
+
+	tasks, _ := listTestTrash(t, router, "")
+	assert.Equal(t, newestFirst, trashedIDs(tasks))
+	assert.NotContains(t, trashedIDs(tasks), kept.ID)
+	if assert.NotEmpty(t, tasks) && assert.NotNil(t, tasks[0].DeletedAt) {
+		assert.Equal(t, "2026-01-03T10:00:00Z", tasks[0].DeletedAt.String())
+	}
+
+	// Cursor pages follow the deletion order, not the ids.
+	var paged []int
+	query := "?cursor=&limit=1"
+	for i := 0; i < 5 && query != ""; i++ {
+		tasks, w := listTestTrash(t, router, query)
+		paged = append(paged, trashedIDs(tasks)...)
+		query = ""
+		if link := w.Header().Get("Link"); link != "" {
+			match := nextLinkPattern.FindStringSubmatch(link)
+			if assert.NotNil(t, match, link) {
+				query = strings.TrimPrefix(match[1], "/api/v1/tasks/trash")
+			}
+		}
+	}
+	assert.Equal(t, newestFirst, paged)
+
+	tasks, w := listTestTrash(t, router, "?limit=2&offset=1")
+	assert.Equal(t, newestFirst[1:], trashedIDs(tasks))
+	assert.Equal(t, "3", w.Header().Get("X-Total-Count"))
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/tasks/%d/restore", deleted[0].ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	tasks, _ = listTestTrash(t, router, "")
+	assert.Equal(t, []int{deleted[2].ID, deleted[1].ID}, trashedIDs(tasks))
+	assert.Nil(t, getTestTask(t, router, deleted[0].ID).DeletedAt, "deleted_at is only shown in the trash")
+}
+
+func purgeTestRequest(t *testing.T, router *gin.Engine, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/tasks"+path, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPurgeTask(t *testing.T) {
+	router := setupTestRouter()
+	task := createTestTask(t, router, Task{Title: "Gone for good", Tags: []string{"old"}})
+
+	w := purgeTestRequest(t, router, fmt.Sprintf("/%d/purge", task.ID))
+	assert.Equal(t, 409, w.Code, "live tasks must be deleted first")
+
+	assert.Equal(t, 200, purgeTestRequest(t, router, fmt.Sprintf("/%d", task.ID)).Code)
+	w = purgeTestRequest(t, router, fmt.Sprintf("/%d/purge", task.ID))
+	assert.Equal(t, 200, w.Code, w.Body.String())
+
+	var remaining int
+	assert.NoError(t, db().QueryRow("SELECT COUNT(*) FROM tasks WHERE id = ?", task.ID).Scan(&remaining))
+	assert.Equal(t, 0, remaining)
+	assert.NoError(t, db().QueryRow("SELECT COUNT(*) FROM task_tags WHERE task_id = ?", task.ID).Scan(&remaining))
+	assert.Equal(t, 0, remaining, "tags go with the task")
+
+	assert.Equal(t, 404, purgeTestRequest(t, router, fmt.Sprintf("/%d/purge", task.ID)).Code)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	router := setupTestRouter()
+	live := createTestTask(t, router, Task{Title: "Live"})
+	var tasks []Task
+	for i, age := range []int{-40, -10, -1} {
+		task := createTestTask(t, router, Task{Title: fmt.Sprintf("Deleted %d", i)})
+		at := time.Now().UTC().AddDate(0, 0, age).Format("2006-01-02 15:04:05")
+		_, err := db().Exec("UPDATE tasks SET deleted_at = ? WHERE id = ?", at, task.ID)
+		assert.NoError(t, err)
+		tasks = append(tasks, task)
+	}
+
+	purged := func(query string) int {
+		w := purgeTestRequest(t, router, "/trash"+query)
+		assert.Equal(t, 200, w.Code, w.Body.String())
+		var response map[string]int
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response["purged"]
+	}
+
+	// The default retention is 30 days, so only the oldest goes.
+	assert.Equal(t, 1, purged(""))
+	remaining, _ := listTestTrash(t, router, "")
+	assert.Equal(t, []int{tasks[2].ID, tasks[1].ID}, trashedIDs(remaining))
+
+	config().Trash.RetentionDays = 5
+	defer func() { config().Trash.RetentionDays = 0 }()
+	assert.Equal(t, 1, purged(""))
+	assert.Equal(t, 0, purged("?older_than=48h"))
+	assert.Equal(t, 1, purged("?older_than=0d"))
+	remaining, _ = listTestTrash(t, router, "")
+	assert.Empty(t, remaining)
+	assert.Equal(t, "Live", getTestTask(t, router, live.ID).Title)
+
+	for _, olderThan := range []string{"soon", "-1d", "30days", ""} {
+		w := purgeTestRequest(t, router, "/trash?older_than="+olderThan)
+		assert.Equal(t, 400, w.Code, olderThan)
+		assert.Contains(t, w.Body.String(), errCodeValidation, olderThan)
+	}
+}
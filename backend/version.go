@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// buildTime and gitCommit describe the build and are injected by the linker:
+//
+//	go build -ldflags "-X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ) -X main.gitCommit=$(git rev-parse HEAD)"
+//
+// When they are left empty, the VCS details the Go toolchain stamps into
+// binaries built inside a git checkout are used instead.
+var (
+	buildTime string
+	gitCommit string
+)
+
+// VersionResponse says what is running, as opposed to HealthResponse, which
+// says whether it is working.
+type VersionResponse struct {
+	XMLName     xml.Name `json:"-" xml:"version"`
+	Name        string   `json:"name" xml:"name"`
+	Version     string   `json:"version" xml:"version"`
+	Environment string   `json:"environment" xml:"environment"`
+	GoVersion   string   `json:"go_version" xml:"go_version"`
+	BuildTime   string   `json:"build_time" xml:"build_time"`
+	GitCommit   string   `json:"git_commit" xml:"git_commit"`
+}
+
+// buildInfo returns the build time and commit, falling back to the toolchain's
+// VCS stamp and then to "unknown".
+func buildInfo() (string, string) {
+	built, commit := buildTime, gitCommit
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.time" && built == "":
+				built = setting.Value
+			case setting.Key == "vcs.revision" && commit == "":
+				commit = setting.Value
+			}
+		}
+	}
+	if built == "" {
+		built = "unknown"
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	return built, commit
+}
+
+func getVersion(c *gin.Context) {
+	built, commit := buildInfo()
+	respondRead(c, http.StatusOK, VersionResponse{
+		Name:        config().App.Name,
+		Version:     config().App.Version,
+		Environment: config().App.Environment,
+		GoVersion:   runtime.Version(),
+		BuildTime:   built,
+		GitCommit:   commit,
+	})
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWebhookQueueSize   = 1000
+	defaultWebhookWorkers     = 4
+	defaultWebhookMaxAttempts = 5
+	webhookTimeout            = 10 * time.Second
+	webhookBaseBackoff        = time.Second
+	// webhookPollInterval is how often idle workers look for deliveries that
+	// came due; new events wake them straight away.
+	webhookPollInterval = time.Second
+	// webhookLease is how long a claimed delivery is hidden from other
+	// workers. A delivery whose worker died with the process becomes due
+	// again once it runs out.
+	webhookLease = 2 * webhookTimeout
+	// webhookPersistBatch is the most events stored in one transaction.
+	webhookPersistBatch = 100
+
+	webhookEventHeader     = "X-Taskhub-Event"
+	webhookSignatureHeader = "X-Taskhub-Signature"
+)
+
+// webhooks is the running dispatcher, or nil when webhooks.urls is empty.
+var webhooks *webhookDispatcher
+
+type webhookDelivery struct {
+	id       int
+	url      string
+	event    string
+	body     []byte
+	attempts int
+}
+
+// webhookDispatcher POSTs task events to the configured URLs from a fixed
+// pool of workers. Published events are buffered in memory and stored in
+// webhook_deliveries in the background, so publishing never waits on the
+// database; once stored, deliveries stay until they succeed and retries
+// survive a restart. Events still buffered when the process dies are lost,
+// but a graceful shutdown stores them through flush.
+//
+// The queue is bounded: when a slow endpoint lets it fill up, new deliveries
+// are dropped with a warning rather than growing the table without limit.
+type webhookDispatcher struct {
+	urls        []string
+	secret      string
+	client      *http.Client
+	maxPending  int
+	maxAttempts int
+	backoff     time.Duration
+	poll        time.Duration
+	// events holds published events until persist stores them.
+	events chan TaskEvent
+	// persisting tracks the running persist goroutine so flush can wait
+	// for a batch it is in the middle of storing.
+	persisting sync.WaitGroup
+	// wake nudges an idle worker when a delivery is stored.
+	wake chan struct{}
+}
+
+func newWebhookDispatcher(urls []string, secret string, maxPending, maxAttempts int) *webhookDispatcher {
+	return &webhookDispatcher{
+		urls:        urls,
+		secret:      secret,
+		client:      &http.Client{Timeout: webhookTimeout},
+		maxPending:  maxPending,
+		maxAttempts: maxAttempts,
+		backoff:     webhookBaseBackoff,
+		poll:        webhookPollInterval,
+		events:      make(chan TaskEvent, maxPending),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// startWebhooks starts the dispatcher workers when webhooks are configured.
+// They stop with ctx; pending deliveries stay in the database and are picked
+// up again on the next start. Call flushWebhooks once the server has stopped
+// to store events published since.
+func startWebhooks(ctx context.Context) {
+	cfg := config().Webhooks
+	if len(cfg.URLs) == 0 {
+		return
+	}
+
+	queueSize, workers, maxAttempts := cfg.QueueSize, cfg.Workers, cfg.MaxAttempts
+	if queueSize <= 0 {
+		queueSize = defaultWebhookQueueSize
+	}
+	if workers <= 0 {
+		workers = defaultWebhookWorkers
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = defaultWebhookMaxAttempts
+	}
+
+	webhooks = newWebhookDispatcher(cfg.URLs, cfg.Secret, queueSize, maxAttempts)
+	webhooks.startPersist(ctx)
+	for i := 0; i < workers; i++ {
+		go webhooks.run(ctx)
+	}
+	logger.Info("webhooks enabled", "urls", len(cfg.URLs), "workers", workers)
+}
+
+// enqueue buffers event for persist without blocking; when the buffer is
+// full the event is dropped with a warning. It is safe to call on a nil
+// dispatcher, which drops the event.
+func (d *webhookDispatcher) enqueue(event TaskEvent) {
+	if d == nil {
+		return
+	}
+	select {
+	case d.events <- event:
+	default:
+		logger.Warn("webhook queue full, dropping event", "event", event.Event)
+	}
+}
+
+func (d *webhookDispatcher) startPersist(ctx context.Context) {
+	d.persisting.Add(1)
+	go func() {
+		defer d.persisting.Done()
+		d.persist(ctx)
+	}()
+}
+
+// persist stores buffered events as deliveries, a batch at a time, until ctx
+// is done.
+func (d *webhookDispatcher) persist(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-d.events:
+			d.store(d.drain(event))
+		}
+	}
+}
+
+// drain returns first followed by whatever else is buffered, up to
+// webhookPersistBatch events.
+func (d *webhookDispatcher) drain(first TaskEvent) []TaskEvent {
+	batch := []TaskEvent{first}
+	for len(batch) < webhookPersistBatch {
+		select {
+		case event := <-d.events:
+			batch = append(batch, event)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// flushWebhooks stores the events still buffered by the running dispatcher.
+// Shutdown calls it after the server stops taking requests and before the
+// database is closed.
+func flushWebhooks() {
+	webhooks.flush()
+}
+
+func (d *webhookDispatcher) flush() {
+	if d == nil {
+		return
+	}
+	d.persisting.Wait()
+	for {
+		select {
+		case event := <-d.events:
+			d.store(d.drain(event))
+		default:
+			return
+		}
+	}
+}
+
+// store inserts one delivery per URL for each event in a single transaction
+// and wakes a worker. Deliveries past maxPending are dropped. It doesn't use
+// the persist context, so a batch taken off the buffer at shutdown is still
+// written.
+func (d *webhookDispatcher) store(batch []TaskEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), databaseTimeout())
+	defer cancel()
+	defer holdWrites()()
+
+	err := func() error {
+		tx, err := db().BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		// Served by idx_webhook_deliveries_due, and run once per batch.
+		var pending int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_deliveries WHERE failed_at IS NULL").Scan(&pending); err != nil {
+			return err
+		}
+		now := time.Now().UTC().Format(time.RFC3339Nano)
+		for _, event := range batch {
+			body, err := json.Marshal(event)
+			if err != nil {
+				logger.Error("failed to encode webhook event", "event", event.Event, "error", err)
+				continue
+			}
+			for _, url := range d.urls {
+				if pending >= d.maxPending {
+					logger.Warn("webhook queue full, dropping delivery", "event", event.Event, "url", url)
+					continue
+				}
+				if _, err := tx.ExecContext(ctx, "INSERT INTO webhook_deliveries (url, event, body, next_attempt_at) VALUES (?, ?, ?, ?)", url, event.Event, string(body), now); err != nil {
+					return err
+				}
+				pending++
+			}
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		logger.Error("failed to queue webhook deliveries", "events", len(batch), "error", err)
+		return
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *webhookDispatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(d.poll)
+	defer ticker.Stop()
+	for {
+		// Drain everything that is due before going back to sleep.
+		for {
+			delivery, ok, err := d.claim(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Error("failed to claim webhook delivery", "error", err)
+				}
+				break
+			}
+			if !ok {
+				break
+			}
+			d.deliver(ctx, delivery)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim takes the delivery that has been due the longest and leases it to
+// this worker by pushing its next attempt past webhookLease. The outer
+// conditions are checked again on the row itself, so two workers racing for
+// the same delivery can't both win.
+func (d *webhookDispatcher) claim(ctx context.Context) (webhookDelivery, bool, error) {
+	defer holdWrites()()
+	now := time.Now().UTC()
+	due := "failed_at IS NULL AND " + timestampCompare("next_attempt_at", "<=")
+	nowArg := now.Format(time.RFC3339Nano)
+
+	var delivery webhookDelivery
+	var body string
+	err := db().QueryRowContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = (SELECT id FROM webhook_deliveries WHERE "+due+" ORDER BY next_attempt_at, id LIMIT 1) AND "+due+" RETURNING id, url, event, body, attempts",
+		now.Add(webhookLease).Format(time.RFC3339Nano), nowArg, nowArg).Scan(&delivery.id, &delivery.url, &delivery.event, &body, &delivery.attempts)
+	if err == sql.ErrNoRows {
+		return delivery, false, nil
+	}
+	if err != nil {
+		return delivery, false, err
+	}
+	delivery.body = []byte(body)
+	return delivery, true, nil
+}
+
+// deliver makes one attempt at a claimed delivery. Success removes it; a
+// non-2xx response or transport error schedules the next attempt with
+// exponential backoff, until maxAttempts marks it failed. Failed deliveries
+// are kept for inspection.
+func (d *webhookDispatcher) deliver(ctx context.Context, delivery webhookDelivery) {
+	postErr := d.post(ctx, delivery)
+	if ctx.Err() != nil {
+		// Shutting down; the lease runs out and the next start retries it.
+		return
+	}
+
+	defer holdWrites()()
+	var err error
+	if postErr == nil {
+		_, err = db().ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE id = ?", delivery.id)
+	} else if attempts := delivery.attempts + 1; attempts >= d.maxAttempts {
+		logger.Error("webhook delivery failed", "event", delivery.event, "url", delivery.url, "attempts", attempts, "error", postErr)
+		_, err = db().ExecContext(ctx, "UPDATE webhook_deliveries SET attempts = ?, last_error = ?, failed_at = ? WHERE id = ?",
+			attempts, postErr.Error(), time.Now().UTC().Format(time.RFC3339Nano), delivery.id)
+	} else {
+		next := time.Now().UTC().Add(d.backoff << (attempts - 1))
+		_, err = db().ExecContext(ctx, "UPDATE webhook_deliveries SET attempts = ?, last_error = ?, next_attempt_at = ? WHERE id = ?",
+			attempts, postErr.Error(), next.Format(time.RFC3339Nano), delivery.id)
+	}
+	if err != nil {
+		logger.Error("failed to record webhook delivery", "event", delivery.event, "url", delivery.url, "error", err)
+	}
+}
+
+func (d *webhookDispatcher) post(ctx context.Context, delivery webhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.url, bytes.NewReader(delivery.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, delivery.event)
+	if d.secret != "" {
+		req.Header.Set(webhookSignatureHeader, signWebhook(d.secret, delivery.body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookDeliveryCounts returns how many deliveries are waiting to be sent
+// or retried and how many gave up after max_attempts.
+func webhookDeliveryCounts(ctx context.Context) (pending, failed int, err error) {
+	err = db().QueryRowContext(ctx, "SELECT COUNT(*) - COUNT(failed_at), COUNT(failed_at) FROM webhook_deliveries").Scan(&pending, &failed)
+	return pending, failed, err
+}
+
+// signWebhook returns the X-Taskhub-Signature value for body: "sha256="
+// followed by the hex HMAC-SHA256 of the raw body under the shared secret.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// startTestWebhooks points the dispatcher at handler and runs one worker for
+// the duration of the test.
+func startTestWebhooks(t *testing.T, secret string, handler http.HandlerFunc) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	ctx, cancel := context.WithCancel(context.Background())
+	webhooks = newWebhookDispatcher([]string{server.URL}, secret, 10, 3)
+	webhooks.backoff = time.Millisecond
+	webhooks.poll = 10 * time.Millisecond
+	webhooks.startPersist(ctx)
+	go webhooks.run(ctx)
+
+	t.Cleanup(func() {
+		cancel()
+		server.Close()
+		webhooks = nil
+	})
+}
+
+func TestWebhookDeliversSignedEvents(t *testing.T) {
+	router := setupTestRouter()
+	received := make(chan receivedWebhook, 10)
+	startTestWebhooks(t, "s3cret", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{header: r.Header, body: body}
+	})
+
+	task := createTestTask(t, router, Task{Title: "Announce me"})
+
+	select {
+	case hook := <-received:
+		assert.Equal(t, eventTaskCreated, hook.header.Get(webhookEventHeader))
+		assert.Equal(t, signWebhook("s3cret", hook.body), hook.header.Get(webhookSignatureHeader))
+
+		var event TaskEvent
+		assert.NoError(t, json.Unmarshal(hook.body, &event))
+		assert.Equal(t, eventTaskCreated, event.Event)
+		assert.Equal(t, task.ID, event.Task.ID)
+		assert.Equal(t, "Announce me", event.Task.Title)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}
+
+func TestWebhookRetriesFailedDeliveries(t *testing.T) {
+	setupTestRouter()
+	var attempts int32
+	done := make(chan struct{})
+	startTestWebhooks(t, "", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		assert.Empty(t, r.Header.Get(webhookSignatureHeader), "unsigned without a secret")
+		close(done)
+	})
+
+	publishTaskEvent(eventTaskDeleted, Task{ID: 1})
+
+	select {
+	case <-done:
+		assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	case <-time.After(5 * time.Second):
+		t.Fatalf("delivery did not succeed, %d attempts", atomic.LoadInt32(&attempts))
+	}
+}
+
+func TestWebhookQueueDropsWhenFull(t *testing.T) {
+	setupTestRouter()
+	// No workers, so nothing drains the queue.
+	webhooks = newWebhookDispatcher([]string{"http://example.invalid"}, "", 1, 1)
+	defer func() { webhooks = nil }()
+
+	// The buffer holds one event; the rest are dropped without waiting.
+	for i := 0; i < 3; i++ {
+		publishTaskEvent(eventTaskUpdated, Task{ID: i})
+	}
+	webhooks.flush()
+	pending, _, err := webhookDeliveryCounts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pending)
+
+	// The table holds one pending delivery too.
+	publishTaskEvent(eventTaskUpdated, Task{ID: 3})
+	webhooks.flush()
+	pending, _, err = webhookDeliveryCounts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pending)
+}
+
+func TestWebhookPublishDoesNotWaitForDatabase(t *testing.T) {
+	setupTestRouter()
+	webhooks = newWebhookDispatcher([]string{"http://example.invalid"}, "", 10, 1)
+	defer func() { webhooks = nil }()
+
+	// Hold the write lock, as a long write transaction would.
+	tx, err := db().Begin()
+	assert.NoError(t, err)
+	_, err = tx.Exec("UPDATE tasks SET title = title")
+	assert.NoError(t, err)
+
+	published := make(chan struct{})
+	go func() {
+		publishTaskEvent(eventTaskCreated, Task{ID: 1})
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publishing waited on the database")
+	}
+
+	assert.NoError(t, tx.Rollback())
+	webhooks.flush()
+	pending, _, err := webhookDeliveryCounts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pending, "the buffered event is stored once the lock is released")
+}
+
+func TestWebhookDeliveriesSurviveRestart(t *testing.T) {
+	setupTestRouter()
+	// Queued by a previous run that stopped before sending it.
+	webhooks = newWebhookDispatcher([]string{"http://example.invalid"}, "", 10, 3)
+	publishTaskEvent(eventTaskCreated, Task{ID: 7, Title: "Left behind"})
+	webhooks.flush()
+	webhooks = nil
+
+	received := make(chan receivedWebhook, 1)
+	startTestWebhooks(t, "", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{header: r.Header, body: body}
+	})
+	_, err := db().Exec("UPDATE webhook_deliveries SET url = ?", webhooks.urls[0])
+	assert.NoError(t, err)
+
+	select {
+	case hook := <-received:
+		var event TaskEvent
+		assert.NoError(t, json.Unmarshal(hook.body, &event))
+		assert.Equal(t, "Left behind", event.Task.Title)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stored delivery was not sent")
+	}
+	assert.Eventually(t, func() bool {
+		pending, _, err := webhookDeliveryCounts(context.Background())
+		return err == nil && pending == 0
+	}, 5*time.Second, 10*time.Millisecond, "a sent delivery is removed")
+}
+
+func TestWebhookDeliveryFailsAfterMaxAttempts(t *testing.T) {
+	setupTestRouter()
+	var attempts int32
+	startTestWebhooks(t, "", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	publishTaskEvent(eventTaskUpdated, Task{ID: 1})
+
+	assert.Eventually(t, func() bool {
+		_, failed, err := webhookDeliveryCounts(context.Background())
+		return err == nil && failed == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	var stored int
+	var lastError string
+	assert.NoError(t, db().QueryRow("SELECT attempts, last_error FROM webhook_deliveries").Scan(&stored, &lastError))
+	assert.Equal(t, 3, stored)
+	assert.Equal(t, "unexpected status 500", lastError)
+}
@@ -0,0 +1,54 @@
+package main
+
+import "sort"
+
+// statusTransitions is the task workflow: the statuses each status may move
+// to. Staying in the same status is always allowed, and small tasks may be
+// completed without being started. Completed tasks can only be reopened to
+// the statuses listed in workflow.reopen_to.
+var statusTransitions = map[string][]string{
+	"pending":     {"in_progress", "completed"},
+	"in_progress": {"pending", "completed"},
+	"completed":   nil,
+}
+
+// nextStatuses returns the statuses a task in status from may move to, other
+// than staying put, in sorted order.
+func nextStatuses(from string) []string {
+	next := append([]string{}, statusTransitions[from]...)
+	if from == "completed" {
+		for _, status := range config().Workflow.ReopenTo {
+			if status = normalizeStatus(status); validStatuses[status] && status != from {
+				next = append(next, status)
+			}
+		}
+	}
+	sort.Strings(next)
+	return next
+}
+
+// canTransition reports whether a task may move from one status to another.
+func canTransition(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, status := range nextStatuses(from) {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// statusesLeadingTo returns every status a task may be in to move to status,
+// including status itself.
+func statusesLeadingTo(status string) []string {
+	var from []string
+	for candidate := range statusTransitions {
+		if canTransition(candidate, status) {
+			from = append(from, candidate)
+		}
+	}
+	sort.Strings(from)
+	return from
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransition(t *testing.T) {
+	setupTestRouter()
+
+	assert.True(t, canTransition("pending", "in_progress"))
+	assert.True(t, canTransition("in_progress", "completed"))
+	assert.True(t, canTransition("in_progress", "pending"))
+	assert.True(t, canTransition("completed", "completed"))
+	assert.False(t, canTransition("completed", "pending"))
+	assert.False(t, canTransition("completed", "in_progress"))
+	assert.Equal(t, []string{"completed", "in_progress", "pending"}, statusesLeadingTo("completed"))
+
+	config().Workflow.ReopenTo = []string{"In_Progress"}
+	assert.True(t, canTransition("completed", "in_progress"))
+	assert.False(t, canTransition("completed", "pending"))
+	assert.Equal(t, []string{"in_progress"}, nextStatuses("completed"))
+}
+
+func putTestStatus(t *testing.T, router *gin.Engine, task Task, status string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]interface{}{"title": task.Title, "status": status, "version": task.Version})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/v1/tasks/"+strconv.Itoa(task.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateTaskStatusTransitions(t *testing.T) {
+	router := setupTestRouter()
+	task := createTestTask(t, router, Task{Title: "Workflow"})
+
+	w := putTestStatus(t, router, task, "in_progress")
+	assert.Equal(t, 200, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &task)
+
+	w = putTestStatus(t, router, task, "completed")
+	assert.Equal(t, 200, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &task)
+
+	w = putTestStatus(t, router, task, "pending")
+	assert.Equal(t, 409, w.Code)
+	var apiErr APIError
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+	assert.Equal(t, errCodeConflict, apiErr.Code)
+	assert.Equal(t, map[string]interface{}{"from": "completed", "to": "pending", "allowed": []interface{}{}}, apiErr.Details)
+
+	w = putTestStatus(t, router, task, "")
+	assert.Equal(t, 200, w.Code, "an omitted status keeps the current one")
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.Equal(t, "completed", task.Status)
+}
+
+func TestBulkUpdateStatusSkipsIllegalTransitions(t *testing.T) {
+	router := setupTestRouter()
+	done := createTestTask(t, router, Task{Title: "Done", Status: "completed"})
+	open := createTestTask(t, router, Task{Title: "Open", Status: "in_progress"})
+
+	w := httptest.NewRecorder()
+	body := `{"ids":[` + strconv.Itoa(done.ID) + `,` + strconv.Itoa(open.ID) + `],"status":"pending"}`
+	req, _ := http.NewRequest("POST", "/api/v1/tasks/bulk-status", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"updated":1}`, w.Body.String())
+
+	assert.Equal(t, 1, len(listTestTasks(t, router, "?q=Done&status=completed")))
+}
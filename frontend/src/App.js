@@ -27,7 +27,7 @@ function App() {
       const response = await axios.get(`${API_BASE_URL}/tasks`);
       setTasks(response.data);
     } catch (err) {
-      setError('Failed to fetch tasks: ' + (err.response?.data?.error || err.message));
+      setError('Failed to fetch tasks: ' + (err.response?.data?.message || err.message));
     } finally {
       setLoading(false);
     }
@@ -50,7 +50,7 @@ function App() {
       setNewTask({ title: '', description: '', status: 'pending' });
       setSuccess('Task created successfully!');
     } catch (err) {
-      setError('Failed to create task: ' + (err.response?.data?.error || err.message));
+      setError('Failed to create task: ' + (err.response?.data?.message || err.message));
     } finally {
       setSubmitting(false);
     }
@@ -68,7 +68,7 @@ function App() {
       setEditingTask(null);
       setSuccess('Task updated successfully!');
     } catch (err) {
-      setError('Failed to update task: ' + (err.response?.data?.error || err.message));
+      setError('Failed to update task: ' + (err.response?.data?.message || err.message));
     }
   };
 
@@ -85,7 +85,7 @@ function App() {
       setTasks(tasks.filter(task => task.id !== taskId));
       setSuccess('Task deleted successfully!');
     } catch (err) {
-      setError('Failed to delete task: ' + (err.response?.data?.error || err.message));
+      setError('Failed to delete task: ' + (err.response?.data?.message || err.message));
     }
   };
 